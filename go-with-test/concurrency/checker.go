@@ -0,0 +1,303 @@
+package concurrency
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Result 一次检查的结果
+type Result struct {
+	URL string
+	Up  bool
+	// StatusCode HTTP 状态码，其它探测方式为 0
+	StatusCode int
+	Latency    time.Duration
+	// TLSExpiry 证书过期时间，非 TLS 连接为零值
+	TLSExpiry time.Time
+	Err       error
+}
+
+// Probe 探测一个目标是否可用，应当在 ctx 结束时尽快返回
+type Probe interface {
+	Probe(ctx context.Context, target string) Result
+}
+
+// Probe 让原来的 WebsiteChecker 也可以作为探测方式使用。WebsiteChecker 不接收 ctx，
+// ctx 结束时 Probe 立即返回，wc 在后台继续执行完。
+func (wc WebsiteChecker) Probe(ctx context.Context, target string) Result {
+	start := time.Now()
+	done := make(chan bool, 1)
+	go func() {
+		done <- wc(target)
+	}()
+	select {
+	case up := <-done:
+		return Result{URL: target, Up: up, Latency: time.Since(start)}
+	case <-ctx.Done():
+		return Result{URL: target, Latency: time.Since(start), Err: ctx.Err()}
+	}
+}
+
+// maxDrainBytes 关闭响应前最多读取并丢弃的字节数
+const maxDrainBytes = 64 << 10
+
+// HTTPProbe 发送 HTTP 请求，状态码满足 Expect 时认为可用
+type HTTPProbe struct {
+	// Client 为 nil 时使用 http.DefaultClient
+	Client *http.Client
+	// Method 默认 GET
+	Method string
+	// Expect 默认接受 2xx 与 3xx
+	Expect func(status int) bool
+}
+
+func (p HTTPProbe) Probe(ctx context.Context, target string) Result {
+	res := Result{URL: target}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	res.Latency = time.Since(start)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer func() {
+		// 读完剩余的响应体才能复用连接，限制读取的长度，避免被很大的响应拖住
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+		resp.Body.Close()
+	}()
+
+	res.StatusCode = resp.StatusCode
+	if resp.TLS != nil {
+		res.TLSExpiry = certExpiry(resp.TLS)
+	}
+	expect := p.Expect
+	if expect == nil {
+		expect = func(status int) bool { return status >= 200 && status < 400 }
+	}
+	res.Up = expect(resp.StatusCode)
+	if !res.Up {
+		res.Err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return res
+}
+
+// TCPProbe 只建立 TCP 连接。目标可以是 host:port 或 URL，URL 没有端口时按协议取默认端口；
+// TLS 不为 nil 时还会完成 TLS 握手并记录证书过期时间。
+type TCPProbe struct {
+	Dialer *net.Dialer
+	TLS    *tls.Config
+}
+
+func (p TCPProbe) Probe(ctx context.Context, target string) Result {
+	res := Result{URL: target}
+	addr, err := hostPort(target)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	dialer := p.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		res.Latency = time.Since(start)
+		res.Err = err
+		return res
+	}
+	defer conn.Close()
+
+	if p.TLS != nil {
+		config := p.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			res.Latency = time.Since(start)
+			res.Err = err
+			return res
+		}
+		state := tlsConn.ConnectionState()
+		res.TLSExpiry = certExpiry(&state)
+	}
+	res.Latency = time.Since(start)
+	res.Up = true
+	return res
+}
+
+// DNSProbe 只解析域名，目标可以是域名、host:port 或 URL
+type DNSProbe struct {
+	// Resolver 为 nil 时使用 net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+func (p DNSProbe) Probe(ctx context.Context, target string) Result {
+	res := Result{URL: target}
+	host := target
+	if addr, err := hostPort(target); err == nil {
+		host, _, _ = net.SplitHostPort(addr)
+	}
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, host)
+	res.Latency = time.Since(start)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses for %s", host)
+	}
+	res.Err = err
+	res.Up = err == nil
+	return res
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// hostPort 从 host:port 或 URL 中取出要连接的地址
+func hostPort(target string) (string, error) {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		if u.Port() != "" {
+			return u.Host, nil
+		}
+		port, ok := defaultPorts[u.Scheme]
+		if !ok {
+			return "", fmt.Errorf("no default port for scheme %q in %s", u.Scheme, target)
+		}
+		return net.JoinHostPort(u.Hostname(), port), nil
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func certExpiry(state *tls.ConnectionState) time.Time {
+	if len(state.PeerCertificates) == 0 {
+		return time.Time{}
+	}
+	return state.PeerCertificates[0].NotAfter
+}
+
+// Checker 用固定数量的 worker 检查网站，避免 CheckWebsites 那样每个 URL 一个 goroutine
+type Checker struct {
+	Probe Probe
+	// Workers 同时检查的数量，默认 10
+	Workers int
+	// Timeout 单个 URL 的超时时间，默认 5s
+	Timeout time.Duration
+}
+
+// Stream 边检查边通过信道返回结果，顺序与完成的先后一致。全部完成或者 ctx 结束后信道关闭，
+// ctx 结束后还没开始检查的 URL 不会出现在结果中。
+func (c *Checker) Stream(ctx context.Context, urls []string) <-chan Result {
+	workers := c.Workers
+	if workers <= 0 {
+		workers = 10
+	}
+	if workers > len(urls) {
+		workers = len(urls)
+	}
+
+	jobs := make(chan string)
+	results := make(chan Result)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				// 接收方不再读取时也能退出
+				select {
+				case results <- c.check(ctx, u):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, u := range urls {
+			select {
+			case jobs <- u:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// Check 检查所有 URL，结果与 urls 的顺序一致
+func (c *Checker) Check(ctx context.Context, urls []string) []Result {
+	index := make(map[string][]int, len(urls))
+	for i, u := range urls {
+		index[u] = append(index[u], i)
+	}
+
+	results := make([]Result, len(urls))
+	for r := range c.Stream(ctx, urls) {
+		i := index[r.URL][0]
+		index[r.URL] = index[r.URL][1:]
+		results[i] = r
+	}
+	// 被取消而没有检查的 URL
+	for u, rest := range index {
+		for _, i := range rest {
+			results[i] = Result{URL: u, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+func (c *Checker) check(ctx context.Context, u string) Result {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	probe := c.Probe
+	if probe == nil {
+		probe = HTTPProbe{}
+	}
+	res := probe.Probe(ctx, u)
+	res.URL = u
+	if res.Err == nil && ctx.Err() != nil {
+		res.Err = ctx.Err()
+		res.Up = false
+	}
+	return res
+}
@@ -0,0 +1,212 @@
+package concurrency
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	var inFlight, peak int32
+	checker := WebsiteChecker(func(url string) bool {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return url != "waat://furhurterwe.geds"
+	})
+
+	urls := make([]string, 50)
+	for i := range urls {
+		urls[i] = "http://google.com"
+	}
+	urls[7] = "waat://furhurterwe.geds"
+
+	c := &Checker{Probe: checker, Workers: 4}
+	results := c.Check(context.Background(), urls)
+
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+	for i, r := range results {
+		if r.URL != urls[i] {
+			t.Errorf("results[%d].URL = %q, want %q", i, r.URL, urls[i])
+		}
+		if r.Up != (i != 7) {
+			t.Errorf("results[%d].Up = %v", i, r.Up)
+		}
+	}
+	if peak > 4 {
+		t.Errorf("%d checks ran at once, want at most 4", peak)
+	}
+}
+
+func TestCheckerStream(t *testing.T) {
+	delays := map[string]time.Duration{"slow": 60 * time.Millisecond, "fast": 0, "hang": time.Hour}
+	probe := probeFunc(func(ctx context.Context, target string) Result {
+		select {
+		case <-time.After(delays[target]):
+			return Result{URL: target, Up: true}
+		case <-ctx.Done():
+			return Result{URL: target, Err: ctx.Err()}
+		}
+	})
+
+	c := &Checker{Probe: probe, Workers: 3, Timeout: 100 * time.Millisecond}
+	var order []string
+	for r := range c.Stream(context.Background(), []string{"hang", "slow", "fast"}) {
+		order = append(order, r.URL)
+		if r.URL == "hang" && r.Err != context.DeadlineExceeded {
+			t.Errorf("hang: got %v, want per-URL timeout", r.Err)
+		}
+	}
+	if got := strings.Join(order, ","); got != "fast,slow,hang" {
+		t.Errorf("results arrived as %s, want fast,slow,hang", got)
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &Checker{Probe: probe, Workers: 1}
+		results := c.Stream(ctx, []string{"fast", "hang", "fast", "fast"})
+		<-results
+		cancel()
+		for range results {
+		}
+
+		results2 := c.Check(ctx, []string{"fast", "fast"})
+		for _, r := range results2 {
+			if r.Err != context.Canceled {
+				t.Errorf("got %v, want context.Canceled", r.Err)
+			}
+		}
+	})
+}
+
+func TestHTTPProbe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	probe := HTTPProbe{Client: srv.Client()}
+	res := probe.Probe(context.Background(), srv.URL)
+	if !res.Up || res.StatusCode != http.StatusOK || res.Err != nil {
+		t.Errorf("got %+v", res)
+	}
+	if want := srv.Certificate().NotAfter; !res.TLSExpiry.Equal(want) {
+		t.Errorf("TLSExpiry = %v, want %v", res.TLSExpiry, want)
+	}
+	if res.Latency <= 0 {
+		t.Errorf("Latency = %v", res.Latency)
+	}
+
+	res = probe.Probe(context.Background(), srv.URL+"/down")
+	if res.Up || res.StatusCode != http.StatusServiceUnavailable || res.Err == nil {
+		t.Errorf("got %+v", res)
+	}
+
+	res = probe.Probe(context.Background(), "waat://furhurterwe.geds")
+	if res.Up || res.Err == nil {
+		t.Errorf("got %+v", res)
+	}
+}
+
+// 关闭前只读取有限长度的响应体，无穷无尽的响应不会拖住 Probe
+func TestHTTPProbeEndlessBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("x", 1024))
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	done := make(chan Result, 1)
+	go func() { done <- HTTPProbe{Client: srv.Client()}.Probe(context.Background(), srv.URL) }()
+	select {
+	case res := <-done:
+		if !res.Up {
+			t.Errorf("got %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Probe did not return")
+	}
+}
+
+func TestWebsiteCheckerProbeCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	wc := WebsiteChecker(func(string) bool {
+		<-release
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	res := wc.Probe(ctx, "http://google.com")
+	if res.Up || res.Err != context.DeadlineExceeded {
+		t.Errorf("got %+v", res)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	res := TCPProbe{}.Probe(context.Background(), srv.URL)
+	if !res.Up || res.Err != nil || !res.TLSExpiry.IsZero() {
+		t.Errorf("got %+v", res)
+	}
+
+	probe := TCPProbe{TLS: &tls.Config{InsecureSkipVerify: true}}
+	res = probe.Probe(context.Background(), srv.Listener.Addr().String())
+	if !res.Up || !res.TLSExpiry.Equal(srv.Certificate().NotAfter) {
+		t.Errorf("got %+v", res)
+	}
+
+	// 关闭的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	if res := (TCPProbe{}).Probe(context.Background(), addr); res.Up || res.Err == nil {
+		t.Errorf("got %+v", res)
+	}
+	if res := (TCPProbe{}).Probe(context.Background(), "waat://furhurterwe.geds"); res.Err == nil {
+		t.Error("want error for unknown scheme")
+	}
+}
+
+func TestDNSProbe(t *testing.T) {
+	for _, target := range []string{"localhost", "localhost:80", "http://localhost/x"} {
+		if res := (DNSProbe{}).Probe(context.Background(), target); !res.Up {
+			t.Errorf("%s: got %+v", target, res)
+		}
+	}
+	if res := (DNSProbe{}).Probe(context.Background(), "furhurterwe.invalid"); res.Up || res.Err == nil {
+		t.Errorf("got %+v", res)
+	}
+}
+
+type probeFunc func(ctx context.Context, target string) Result
+
+func (f probeFunc) Probe(ctx context.Context, target string) Result {
+	return f(ctx, target)
+}
@@ -1 +1,3 @@
 module concurrency
+
+go 1.27.1
@@ -0,0 +1,363 @@
+package concurrency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Target 监控目标，每个目标按自己的间隔检查
+type Target struct {
+	URL string
+	// Interval 检查间隔，默认 30s
+	Interval time.Duration
+	// Timeout 单次检查超时，默认 5s
+	Timeout time.Duration
+	// Labels 附加到告警上的标签，例如 team、severity
+	Labels map[string]string
+}
+
+// Sample 一次检查的摘要，保存在滚动历史中
+type Sample struct {
+	At      time.Time     `json:"at"`
+	Up      bool          `json:"up"`
+	Latency time.Duration `json:"latencyNs"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// TargetStatus 状态页中单个目标的状态
+type TargetStatus struct {
+	URL string `json:"url"`
+	// Known 为 false 表示还没有检查过，此时 Up 没有意义
+	Known      bool       `json:"known"`
+	Up         bool       `json:"up"`
+	Since      time.Time  `json:"since"`
+	Uptime     float64    `json:"uptime"`
+	Checks     int        `json:"checks"`
+	LastCheck  time.Time  `json:"lastCheck"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	TLSExpiry  *time.Time `json:"tlsExpiry,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+	History    []Sample   `json:"history"`
+}
+
+// Monitor 持续检查一组目标，状态变化时向 Alertmanager 兼容的 webhook（如 prometheus/alert）发送告警
+type Monitor struct {
+	// Probe 默认 HTTPProbe
+	Probe Probe
+	// WebhookURL 为空时不发送告警
+	WebhookURL string
+	// Client 发送告警用的客户端，默认使用带超时的 defaultWebhookClient
+	Client *http.Client
+	// WebhookTimeout 单次发送告警的超时时间，默认 5s。告警在检查的 goroutine 中同步发送以保证顺序，
+	// 超时避免 webhook 无响应时拖住后续的检查
+	WebhookTimeout time.Duration
+	// HistorySize 每个目标保留的检查记录数，默认 100，Uptime 按这些记录计算
+	HistorySize int
+	// OnError 发送告警失败时调用，默认写日志
+	OnError func(error)
+
+	mu      sync.Mutex
+	targets []*targetState
+}
+
+type targetState struct {
+	Target
+	history []Sample
+	last    Result
+	// known 为 false 表示还没有检查过
+	known bool
+	up    bool
+	since time.Time
+	// checks 累计的检查次数，history 只保留最近的
+	checks int
+}
+
+// NewMonitor 创建监控，调用 Run 开始检查
+func NewMonitor(webhookURL string, targets ...Target) *Monitor {
+	m := &Monitor{WebhookURL: webhookURL}
+	for _, t := range targets {
+		m.targets = append(m.targets, &targetState{Target: t})
+	}
+	return m
+}
+
+// Run 每个目标一个 goroutine，启动时立即检查一次，之后按各自的间隔检查，直到 ctx 结束
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range m.targets {
+		wg.Add(1)
+		go func(t *targetState) {
+			defer wg.Done()
+			interval := t.Interval
+			if interval <= 0 {
+				interval = 30 * time.Second
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				m.check(ctx, t)
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+// CheckAll 立即并发检查所有目标一次并等待完成
+func (m *Monitor) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range m.targets {
+		wg.Add(1)
+		go func(t *targetState) {
+			defer wg.Done()
+			m.check(ctx, t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (m *Monitor) check(ctx context.Context, t *targetState) {
+	checker := Checker{Probe: m.Probe, Timeout: t.Timeout}
+	res := checker.check(ctx, t.URL)
+	// 监控本身被停止导致的失败不记录
+	if ctx.Err() != nil {
+		return
+	}
+	if res.Up {
+		// 成功的检查 Err 为空
+		res.Err = nil
+	}
+
+	now := time.Now()
+	sample := Sample{At: now, Up: res.Up, Latency: res.Latency}
+	if res.Err != nil {
+		sample.Error = res.Err.Error()
+	}
+
+	m.mu.Lock()
+	size := m.HistorySize
+	if size <= 0 {
+		size = 100
+	}
+	t.history = append(t.history, sample)
+	if len(t.history) > size {
+		t.history = append(t.history[:0], t.history[len(t.history)-size:]...)
+	}
+	t.checks++
+	t.last = res
+
+	var alert *Alert
+	switch {
+	case !t.known:
+		// 第一次检查就失败也需要告警，第一次成功则不需要恢复通知
+		if !res.Up {
+			alert = m.alert(t, now)
+		}
+		t.known, t.up, t.since = true, res.Up, now
+	case t.up != res.Up:
+		alert = m.alert(t, now)
+		t.up, t.since = res.Up, now
+	}
+	m.mu.Unlock()
+
+	if alert != nil {
+		if err := m.notify(ctx, alert); err != nil {
+			m.onError(err)
+		}
+	}
+}
+
+// Alert Alertmanager webhook 中的单条告警
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// WebhookMessage Alertmanager webhook 的请求体 (version 4)
+type WebhookMessage struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// alert 根据目标当前状态生成告警：由 up 变为 down 是 firing，由 down 变为 up 是 resolved，调用方需持有 m.mu
+func (m *Monitor) alert(t *targetState, now time.Time) *Alert {
+	labels := map[string]string{"alertname": "WebsiteDown", "instance": t.URL}
+	for k, v := range t.Labels {
+		labels[k] = v
+	}
+	a := &Alert{
+		Labels:       labels,
+		Annotations:  map[string]string{},
+		GeneratorURL: t.URL,
+		Fingerprint:  fingerprint(labels),
+	}
+	if t.known && !t.up {
+		// 恢复通知带上故障开始的时间，接收方据此关联同一次故障
+		a.Status = "resolved"
+		a.StartsAt, a.EndsAt = t.since, now
+		a.Annotations["summary"] = fmt.Sprintf("%s is back up after %s", t.URL, now.Sub(t.since).Round(time.Second))
+	} else {
+		a.Status = "firing"
+		a.StartsAt = now
+		a.Annotations["summary"] = fmt.Sprintf("%s is down", t.URL)
+		if t.last.Err != nil {
+			a.Annotations["description"] = t.last.Err.Error()
+		}
+	}
+	return a
+}
+
+// defaultWebhookClient Monitor.Client 为 nil 时使用，http.DefaultClient 没有超时
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func (m *Monitor) notify(ctx context.Context, a *Alert) error {
+	if m.WebhookURL == "" {
+		return nil
+	}
+	timeout := m.WebhookTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg := WebhookMessage{
+		Version:           "4",
+		GroupKey:          fmt.Sprintf("{}:{instance=%q}", a.Labels["instance"]),
+		Status:            a.Status,
+		Receiver:          "webhook",
+		GroupLabels:       map[string]string{"instance": a.Labels["instance"]},
+		CommonLabels:      a.Labels,
+		CommonAnnotations: a.Annotations,
+		Alerts:            []Alert{*a},
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := m.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send alert for %s: %w", a.Labels["instance"], err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+		resp.Body.Close()
+	}()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send alert for %s: webhook returned %s", a.Labels["instance"], resp.Status)
+	}
+	return nil
+}
+
+func (m *Monitor) onError(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+		return
+	}
+	log.Printf("monitor: %v", err)
+}
+
+// Status 返回所有目标的状态，按 URL 排序
+func (m *Monitor) Status() []TargetStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]TargetStatus, 0, len(m.targets))
+	for _, t := range m.targets {
+		s := TargetStatus{
+			URL:        t.URL,
+			Known:      t.known,
+			Up:         t.up,
+			Since:      t.since,
+			Checks:     t.checks,
+			StatusCode: t.last.StatusCode,
+			History:    append([]Sample{}, t.history...),
+		}
+		if t.last.Err != nil {
+			s.LastError = t.last.Err.Error()
+		}
+		if !t.last.TLSExpiry.IsZero() {
+			expiry := t.last.TLSExpiry
+			s.TLSExpiry = &expiry
+		}
+		if n := len(t.history); n > 0 {
+			s.LastCheck = t.history[n-1].At
+			ups := 0
+			for _, sample := range t.history {
+				if sample.Up {
+					ups++
+				}
+			}
+			s.Uptime = float64(ups) / float64(n)
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].URL < statuses[j].URL })
+	return statuses
+}
+
+// ServeHTTP JSON 状态页，所有检查过的目标都可用时返回 200，否则返回 503，方便被其它监控探测。
+// 还没有检查过的目标不影响状态码，避免刚启动时误报。
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := m.Status()
+	code := http.StatusOK
+	for _, s := range statuses {
+		if s.Known && !s.Up {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(struct {
+		Targets []TargetStatus `json:"targets"`
+	}{statuses})
+}
+
+func fingerprint(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", k, labels[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
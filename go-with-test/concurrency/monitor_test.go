@@ -0,0 +1,212 @@
+package concurrency
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// alertReceiver 模拟 prometheus/alert 的 /webhook
+type alertReceiver struct {
+	mu       sync.Mutex
+	messages []WebhookMessage
+}
+
+func (a *alertReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var msg WebhookMessage
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&msg) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	a.messages = append(a.messages, msg)
+	a.mu.Unlock()
+}
+
+func (a *alertReceiver) received() []WebhookMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]WebhookMessage(nil), a.messages...)
+}
+
+// flappingSite 由 healthy 决定返回 200 还是 500
+func flappingSite(healthy *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+}
+
+func TestMonitorAlerts(t *testing.T) {
+	receiver := &alertReceiver{}
+	webhook := httptest.NewServer(receiver)
+	defer webhook.Close()
+
+	healthy := int32(1)
+	site := flappingSite(&healthy)
+	defer site.Close()
+	stable := flappingSite(new(int32))
+	defer stable.Close()
+
+	m := NewMonitor(webhook.URL+"/webhook",
+		Target{URL: site.URL, Labels: map[string]string{"team": "core"}},
+		Target{URL: stable.URL},
+	)
+	m.HistorySize = 3
+	ctx := context.Background()
+
+	m.CheckAll(ctx)
+	msgs := receiver.received()
+	if len(msgs) != 1 || msgs[0].Status != "firing" || msgs[0].Alerts[0].Labels["instance"] != stable.URL {
+		t.Fatalf("a target that is down on the first check fires once, got %+v", msgs)
+	}
+
+	m.CheckAll(ctx)
+	if n := len(receiver.received()); n != 1 {
+		t.Fatalf("no transition, no alert: got %d messages", n)
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	m.CheckAll(ctx)
+	atomic.StoreInt32(&healthy, 1)
+	m.CheckAll(ctx)
+
+	msgs = receiver.received()
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+	firing, resolved := msgs[1], msgs[2]
+	if firing.Version != "4" || firing.Status != "firing" || resolved.Status != "resolved" {
+		t.Errorf("got %s then %s", firing.Status, resolved.Status)
+	}
+	fa, ra := firing.Alerts[0], resolved.Alerts[0]
+	if fa.Labels["alertname"] != "WebsiteDown" || fa.Labels["team"] != "core" || fa.Labels["instance"] != site.URL {
+		t.Errorf("unexpected labels %v", fa.Labels)
+	}
+	if fa.Fingerprint != ra.Fingerprint || !ra.StartsAt.Equal(fa.StartsAt) || !ra.EndsAt.After(ra.StartsAt) {
+		t.Errorf("resolved alert should match the firing one: %+v %+v", fa, ra)
+	}
+	if fa.Annotations["description"] == "" {
+		t.Error("firing alert should describe the failure")
+	}
+	if !strings.Contains(fa.Annotations["summary"], "is down") || !strings.Contains(ra.Annotations["summary"], "is back up") {
+		t.Errorf("got summaries %q and %q", fa.Annotations["summary"], ra.Annotations["summary"])
+	}
+
+	statuses := m.Status()
+	s := statuses[0]
+	if statuses[1].URL == site.URL {
+		s = statuses[1]
+	}
+	if !s.Up || s.Checks != 4 || len(s.History) != 3 || s.StatusCode != http.StatusOK {
+		t.Errorf("got %+v", s)
+	}
+	if s.Uptime < 0.66 || s.Uptime > 0.67 {
+		t.Errorf("uptime over the last 3 checks = %v, want 2/3", s.Uptime)
+	}
+}
+
+func TestMonitorStatusPage(t *testing.T) {
+	healthy := int32(1)
+	site := flappingSite(&healthy)
+	defer site.Close()
+
+	m := NewMonitor("", Target{URL: site.URL})
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got %d, targets that were never checked should not fail the page", rec.Code)
+	}
+	if s := m.Status()[0]; s.Known {
+		t.Errorf("got %+v", s)
+	}
+
+	m.CheckAll(context.Background())
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var page struct {
+		Targets []TargetStatus `json:"targets"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Targets) != 1 || page.Targets[0].URL != site.URL || page.Targets[0].Uptime != 1 {
+		t.Errorf("got %+v", page)
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	m.CheckAll(context.Background())
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want 503 while a target is down", rec.Code)
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	var fastHits, slowHits int32
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fastHits, 1)
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slowHits, 1)
+	}))
+	defer slow.Close()
+
+	var webhookErrors int32
+	m := NewMonitor("http://127.0.0.1:1/webhook",
+		Target{URL: fast.URL, Interval: 10 * time.Millisecond},
+		Target{URL: slow.URL, Interval: time.Hour},
+	)
+	m.OnError = func(error) { atomic.AddInt32(&webhookErrors, 1) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+
+	if n := atomic.LoadInt32(&fastHits); n < 5 {
+		t.Errorf("fast target checked %d times", n)
+	}
+	if n := atomic.LoadInt32(&slowHits); n != 1 {
+		t.Errorf("slow target checked %d times, want only the initial check", n)
+	}
+	if n := atomic.LoadInt32(&webhookErrors); n != 0 {
+		t.Errorf("healthy targets should not alert, got %d webhook errors", n)
+	}
+}
+
+func TestMonitorWebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer webhook.Close()
+	defer close(release)
+	site := flappingSite(new(int32))
+	defer site.Close()
+
+	var webhookErr error
+	m := NewMonitor(webhook.URL, Target{URL: site.URL})
+	m.WebhookTimeout = 20 * time.Millisecond
+	m.OnError = func(err error) { webhookErr = err }
+
+	start := time.Now()
+	m.CheckAll(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("a hanging webhook blocked the check for %v", elapsed)
+	}
+	if webhookErr == nil {
+		t.Error("expected the webhook to time out")
+	}
+}
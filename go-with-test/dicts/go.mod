@@ -1 +1,3 @@
 module dicts
+
+go 1.27.1
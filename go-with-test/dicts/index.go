@@ -0,0 +1,239 @@
+package dicts
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index 是 Dict 的伴随索引，在精确查找之外提供：
+// 前缀查找、通配符匹配，以及基于倒排索引 + TF-IDF 排序的释义全文检索。
+// 所有写操作都必须经过 Index，这样单词树和倒排索引才能与字典保持一致。
+type Index struct {
+	mu   sync.RWMutex
+	dict Dict
+	// words 单词前缀树
+	words *trie
+	// postings 倒排索引：词项 -> 单词 -> 词频
+	postings map[string]map[string]int
+	// lengths 每个单词释义的词项总数，用于计算 TF
+	lengths map[string]int
+}
+
+// Hit 全文检索的一条命中结果
+type Hit struct {
+	Word       string
+	Definition string
+	Score      float64
+}
+
+// NewIndex 基于已有字典建立索引，dict 为 nil 时创建一个空字典
+func NewIndex(dict Dict) *Index {
+	if dict == nil {
+		dict = Dict{}
+	}
+	idx := &Index{
+		dict:     dict,
+		words:    newTrie(),
+		postings: map[string]map[string]int{},
+		lengths:  map[string]int{},
+	}
+	for word, definition := range dict {
+		idx.words.insert(word)
+		idx.indexDefinition(word, definition)
+	}
+	return idx
+}
+
+func (idx *Index) Search(word string) string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.dict.Search(word)
+}
+
+func (idx *Index) Find(word string) (string, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.dict.Find(word)
+}
+
+// Add 与 Dict.Add 一致：单词已存在时覆盖释义
+func (idx *Index) Add(word, definition string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.put(word, definition)
+}
+
+func (idx *Index) AddErr(word, definition string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, err := idx.dict.Find(word); err == nil {
+		return DictKeyExist
+	}
+	idx.put(word, definition)
+	return nil
+}
+
+func (idx *Index) Update(word, definition string) {
+	idx.Add(word, definition)
+}
+
+func (idx *Index) UpdateErr(word, definition string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, err := idx.dict.Find(word); err != nil {
+		return DictKeyNotExist
+	}
+	idx.put(word, definition)
+	return nil
+}
+
+func (idx *Index) Delete(word string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, ok := idx.dict[word]; !ok {
+		return
+	}
+	idx.unindexDefinition(word)
+	idx.words.remove(word)
+	idx.dict.Delete(word)
+}
+
+// Len 返回已索引的单词数
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.dict)
+}
+
+// PrefixSearch 返回所有以 prefix 开头的单词，按字典序排列
+func (idx *Index) PrefixSearch(prefix string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.words.withPrefix(prefix)
+}
+
+// Match 通配符查找，'*' 匹配任意个字符，'?' 匹配单个字符，结果按字典序排列
+func (idx *Index) Match(pattern string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.words.match(pattern)
+}
+
+// FullText 对释义做全文检索，按 TF-IDF 得分从高到低返回，得分相同时按单词排序。
+// limit <= 0 时返回全部命中。
+func (idx *Index) FullText(query string, limit int) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	total := float64(len(idx.dict))
+	scores := map[string]float64{}
+	for _, term := range uniqueTerms(tokenize(query)) {
+		docs := idx.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		// 平滑后的 IDF，保证只有一个文档时得分仍为正
+		idf := math.Log(1 + total/float64(len(docs)))
+		for word, freq := range docs {
+			tf := float64(freq) / float64(idx.lengths[word])
+			scores[word] += tf * idf
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for word, score := range scores {
+		hits = append(hits, Hit{Word: word, Definition: idx.dict[word], Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Word < hits[j].Word
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// put 写入字典并刷新索引，调用方需持有写锁
+func (idx *Index) put(word, definition string) {
+	if _, ok := idx.dict[word]; ok {
+		idx.unindexDefinition(word)
+	} else {
+		idx.words.insert(word)
+	}
+	idx.dict[word] = definition
+	idx.indexDefinition(word, definition)
+}
+
+func (idx *Index) indexDefinition(word, definition string) {
+	terms := tokenize(definition)
+	for _, term := range terms {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = map[string]int{}
+			idx.postings[term] = docs
+		}
+		docs[word]++
+	}
+	idx.lengths[word] = len(terms)
+}
+
+func (idx *Index) unindexDefinition(word string) {
+	for _, term := range uniqueTerms(tokenize(idx.dict[word])) {
+		docs := idx.postings[term]
+		delete(docs, word)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.lengths, word)
+}
+
+// tokenize 将文本切分为小写词项：字母数字连续串为一个词项，汉字逐字成词
+func tokenize(text string) []string {
+	var (
+		terms []string
+		sb    strings.Builder
+	)
+	flush := func() {
+		if sb.Len() > 0 {
+			terms = append(terms, sb.String())
+			sb.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			out = append(out, term)
+		}
+	}
+	return out
+}
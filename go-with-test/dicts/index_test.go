@@ -0,0 +1,171 @@
+package dicts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestIndex() *Index {
+	return NewIndex(Dict{
+		"go":     "a statically typed compiled programming language",
+		"gopher": "the mascot of the go programming language",
+		"golang": "another name for go",
+		"rust":   "a systems programming language",
+		"gold":   "a precious yellow metal",
+	})
+}
+
+func assertWords(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func hitWords(hits []Hit) []string {
+	var words []string
+	for _, h := range hits {
+		words = append(words, h.Word)
+	}
+	return words
+}
+
+func TestIndexPrefixSearch(t *testing.T) {
+	idx := newTestIndex()
+
+	assertWords(t, idx.PrefixSearch("go"), []string{"go", "golang", "gold", "gopher"})
+	assertWords(t, idx.PrefixSearch("gol"), []string{"golang", "gold"})
+	assertWords(t, idx.PrefixSearch("java"), nil)
+	assertWords(t, idx.PrefixSearch(""), []string{"go", "golang", "gold", "gopher", "rust"})
+}
+
+func TestIndexMatch(t *testing.T) {
+	idx := newTestIndex()
+
+	cases := []struct {
+		pattern string
+		want    []string
+	}{
+		{"go", []string{"go"}},
+		{"go*", []string{"go", "golang", "gold", "gopher"}},
+		{"go??", []string{"gold"}},
+		{"*r", []string{"gopher"}},
+		{"*o*", []string{"go", "golang", "gold", "gopher"}},
+		{"**st", []string{"rust"}},
+		{"?", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			assertWords(t, idx.Match(c.pattern), c.want)
+		})
+	}
+}
+
+func TestIndexFullText(t *testing.T) {
+	idx := newTestIndex()
+
+	t.Run("ranked by tf-idf", func(t *testing.T) {
+		hits := idx.FullText("programming language", 0)
+		// 释义越短，词项占比越高，得分越高
+		assertWords(t, hitWords(hits), []string{"rust", "go", "gopher"})
+	})
+
+	t.Run("rare terms score higher", func(t *testing.T) {
+		hits := idx.FullText("go mascot", 0)
+		assertWords(t, hitWords(hits), []string{"gopher", "golang"})
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		hits := idx.FullText("language", 1)
+		assertWords(t, hitWords(hits), []string{"rust"})
+	})
+
+	t.Run("case and punctuation insensitive", func(t *testing.T) {
+		hits := idx.FullText("PRECIOUS, Metal!", 0)
+		assertWords(t, hitWords(hits), []string{"gold"})
+	})
+
+	t.Run("han characters", func(t *testing.T) {
+		idx := NewIndex(nil)
+		idx.Add("字典", "把单词映射到释义")
+		idx.Add("切片", "动态数组")
+		assertWords(t, hitWords(idx.FullText("释义", 0)), []string{"字典"})
+	})
+}
+
+func TestIndexConsistency(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		idx := newTestIndex()
+		err := idx.AddErr("gone", "no longer present")
+
+		assertError(t, err, nil)
+		assertWords(t, idx.PrefixSearch("gon"), []string{"gone"})
+		assertWords(t, hitWords(idx.FullText("present", 0)), []string{"gone"})
+		assertError(t, idx.AddErr("gone", "again"), DictKeyExist)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		idx := newTestIndex()
+		err := idx.UpdateErr("gold", "a shiny element")
+
+		assertError(t, err, nil)
+		assertWords(t, hitWords(idx.FullText("metal", 0)), nil)
+		assertWords(t, hitWords(idx.FullText("shiny", 0)), []string{"gold"})
+		assertError(t, idx.UpdateErr("silver", "metal"), DictKeyNotExist)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		idx := newTestIndex()
+		idx.Delete("golang")
+		idx.Delete("unknown")
+
+		_, err := idx.Find("golang")
+		assertError(t, err, DictKeyNotFound)
+		assertWords(t, idx.PrefixSearch("gol"), []string{"gold"})
+		assertWords(t, hitWords(idx.FullText("another", 0)), nil)
+		if idx.Len() != 4 {
+			t.Errorf("got %d words, want %d", idx.Len(), 4)
+		}
+	})
+
+	t.Run("delete keeps longer words", func(t *testing.T) {
+		idx := newTestIndex()
+		idx.Delete("go")
+
+		assertWords(t, idx.PrefixSearch("go"), []string{"golang", "gold", "gopher"})
+		assertWords(t, idx.Match("go"), nil)
+	})
+}
+
+// pathologicalIndex 由长串的 a 组成，多个 '*' 的模式在没有记忆化时会指数级回溯
+func pathologicalIndex() *Index {
+	dict := Dict{}
+	for n := 1; n <= 60; n++ {
+		dict[strings.Repeat("a", n)] = "a"
+		dict[strings.Repeat("a", n)+"b"] = "b"
+	}
+	return NewIndex(dict)
+}
+
+func TestIndexMatchPathological(t *testing.T) {
+	idx := pathologicalIndex()
+	got := idx.Match("*a*a*a*a*a*a*a*a*b")
+	if len(got) != 53 || got[len(got)-1] != strings.Repeat("a", 8)+"b" {
+		t.Errorf("got %d words: %v", len(got), got)
+	}
+	if got := idx.Match("***a***"); len(got) != 120 {
+		t.Errorf("got %d words", len(got))
+	}
+}
+
+func BenchmarkIndexMatchPathological(b *testing.B) {
+	idx := pathologicalIndex()
+	for i := 0; i < b.N; i++ {
+		idx.Match("*a*a*a*a*a*a*a*a*b")
+	}
+}
@@ -0,0 +1,158 @@
+package dicts
+
+import "sort"
+
+// trie 前缀树，按 rune 存储单词，用于前缀和通配符查找
+type trie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// terminal 标记从根到当前节点的路径构成一个完整单词
+	terminal bool
+}
+
+func newTrie() *trie {
+	return &trie{root: newTrieNode()}
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: map[rune]*trieNode{}}
+}
+
+func (t *trie) insert(word string) {
+	node := t.root
+	for _, r := range word {
+		child, ok := node.children[r]
+		if !ok {
+			child = newTrieNode()
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.terminal = true
+}
+
+// remove 删除单词，并顺带清理不再有用的分支
+func (t *trie) remove(word string) {
+	t.removeRunes(t.root, []rune(word))
+}
+
+func (t *trie) removeRunes(node *trieNode, runes []rune) bool {
+	if len(runes) == 0 {
+		node.terminal = false
+		return len(node.children) == 0
+	}
+
+	child, ok := node.children[runes[0]]
+	if !ok {
+		return false
+	}
+	if t.removeRunes(child, runes[1:]) {
+		delete(node.children, runes[0])
+	}
+	return !node.terminal && len(node.children) == 0
+}
+
+// find 返回前缀对应的节点，不存在时返回 nil
+func (t *trie) find(prefix string) *trieNode {
+	node := t.root
+	for _, r := range prefix {
+		child, ok := node.children[r]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// withPrefix 返回所有以 prefix 开头的单词，按字典序排列
+func (t *trie) withPrefix(prefix string) []string {
+	node := t.find(prefix)
+	if node == nil {
+		return nil
+	}
+
+	var words []string
+	collect(node, []rune(prefix), func(word string) {
+		words = append(words, word)
+	})
+	sort.Strings(words)
+	return words
+}
+
+// match 返回所有匹配通配符模式的单词：'*' 匹配任意个字符，'?' 匹配单个字符
+func (t *trie) match(pattern string) []string {
+	m := &matcher{pattern: collapseStars([]rune(pattern)), seen: map[matchState]bool{}}
+	m.match(t.root, 0, nil)
+	sort.Strings(m.words)
+	return m.words
+}
+
+func collect(node *trieNode, path []rune, fn func(word string)) {
+	if node.terminal {
+		fn(string(path))
+	}
+	for r, child := range node.children {
+		collect(child, append(path, r), fn)
+	}
+}
+
+// matchState 匹配过程中的状态：trie 中的节点对应唯一的路径，
+// 同一个节点和模式位置的组合只需要搜索一次，多个 '*' 的模式不会指数级回溯，也不会产生重复的结果
+type matchState struct {
+	node *trieNode
+	i    int
+}
+
+type matcher struct {
+	pattern []rune
+	seen    map[matchState]bool
+	words   []string
+}
+
+func (m *matcher) match(node *trieNode, i int, path []rune) {
+	state := matchState{node, i}
+	if m.seen[state] {
+		return
+	}
+	m.seen[state] = true
+
+	if i == len(m.pattern) {
+		if node.terminal {
+			m.words = append(m.words, string(path))
+		}
+		return
+	}
+
+	switch m.pattern[i] {
+	case '*':
+		// '*' 匹配空串，或者吞掉一个字符后继续保持 '*'
+		m.match(node, i+1, path)
+		for r, child := range node.children {
+			m.match(child, i, append(path, r))
+		}
+	case '?':
+		for r, child := range node.children {
+			m.match(child, i+1, append(path, r))
+		}
+	default:
+		if child, ok := node.children[m.pattern[i]]; ok {
+			m.match(child, i+1, append(path, m.pattern[i]))
+		}
+	}
+}
+
+// collapseStars 连续的 '*' 与一个 '*' 等价
+func collapseStars(pattern []rune) []rune {
+	out := make([]rune, 0, len(pattern))
+	for i, r := range pattern {
+		if r == '*' && i > 0 && pattern[i-1] == '*' {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package goroutineexit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var BroadcastClosedError = errors.New("broadcast closed")
+
+// Broadcast 一对多的广播信道：每个订阅者都会收到 Send 的每个值。
+// 与 safeClose、myclose 不同，Close 可以被任意多个 goroutine 调用任意多次，每个 Broadcast 各自只关闭一次，
+// 订阅者的信道由 Broadcast 关闭，发送者不会向已关闭的信道发送。
+type Broadcast[T any] struct {
+	// mu 发送时持有读锁，关闭订阅者的信道时持有写锁，保证不会向已关闭的信道发送
+	mu     sync.RWMutex
+	subs   map[*subscriber[T]]struct{}
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+type subscriber[T any] struct {
+	ch   chan T
+	gone <-chan struct{}
+	stop func() bool
+}
+
+// NewBroadcast 返回没有订阅者的广播信道
+func NewBroadcast[T any]() *Broadcast[T] {
+	return &Broadcast[T]{
+		subs: make(map[*subscriber[T]]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Subscribe 订阅之后发送的值，buffer 为信道的缓冲大小。
+// 返回的信道在 Broadcast 关闭或者 ctx 被取消时关闭，已经关闭的 Broadcast 返回一个已关闭的信道。
+func (b *Broadcast[T]) Subscribe(ctx context.Context, buffer int) <-chan T {
+	sub := &subscriber[T]{ch: make(chan T, buffer), gone: ctx.Done()}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.ch)
+		return sub.ch
+	}
+	b.subs[sub] = struct{}{}
+	sub.stop = context.AfterFunc(ctx, func() { b.unsubscribe(sub) })
+	return sub.ch
+}
+
+func (b *Broadcast[T]) unsubscribe(sub *subscriber[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Send 把 v 发送给所有订阅者，阻塞直到每个订阅者都收到、取消了订阅，或者 ctx 被取消。
+// Broadcast 已经关闭时返回 BroadcastClosedError。
+func (b *Broadcast[T]) Send(ctx context.Context, v T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return BroadcastClosedError
+	}
+	for sub := range b.subs {
+		select {
+		case sub.ch <- v:
+		case <-sub.gone:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.done:
+			return BroadcastClosedError
+		}
+	}
+	return nil
+}
+
+// Close 关闭广播和所有订阅者的信道，只有第一次调用返回 true。
+// 正在阻塞的 Send 会先返回，因此 Close 不会被慢的订阅者卡住。
+func (b *Broadcast[T]) Close() (justClosed bool) {
+	b.once.Do(func() {
+		justClosed = true
+		close(b.done)
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.closed = true
+		for sub := range b.subs {
+			sub.stop()
+			close(sub.ch)
+		}
+		clear(b.subs)
+	})
+	return justClosed
+}
+
+// Done 返回的信道在 Close 之后可读，可以当作只关闭一次的退出信号使用
+func (b *Broadcast[T]) Done() <-chan struct{} {
+	return b.done
+}
@@ -0,0 +1,129 @@
+package goroutineexit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"goroutineexit/leakcheck"
+)
+
+func TestBroadcast(t *testing.T) {
+	leakcheck.Check(t)
+	ctx := context.Background()
+	b := NewBroadcast[int]()
+
+	subs := []<-chan int{b.Subscribe(ctx, 0), b.Subscribe(ctx, 0), b.Subscribe(ctx, 3)}
+	var wg sync.WaitGroup
+	results := make([][]int, len(subs))
+	for i, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range sub {
+				results[i] = append(results[i], v)
+			}
+		}()
+	}
+
+	for i := range 3 {
+		if err := b.Send(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !b.Close() {
+		t.Error("first Close should report closing")
+	}
+	wg.Wait()
+	for i, got := range results {
+		if len(got) != 3 || got[0] != 0 || got[2] != 2 {
+			t.Errorf("subscriber %d got %v", i, got)
+		}
+	}
+
+	if err := b.Send(ctx, 4); !errors.Is(err, BroadcastClosedError) {
+		t.Errorf("got %v", err)
+	}
+	if _, ok := <-b.Subscribe(ctx, 0); ok {
+		t.Error("subscribing after Close should return a closed channel")
+	}
+}
+
+func TestBroadcastCloseConcurrently(t *testing.T) {
+	leakcheck.Check(t)
+	b := NewBroadcast[struct{}]()
+	sub := b.Subscribe(context.Background(), 0)
+
+	var closed sync.WaitGroup
+	var mu sync.Mutex
+	firsts := 0
+	for range 10 {
+		closed.Add(1)
+		go func() {
+			defer closed.Done()
+			if b.Close() {
+				mu.Lock()
+				firsts++
+				mu.Unlock()
+			}
+		}()
+	}
+	closed.Wait()
+	if firsts != 1 {
+		t.Errorf("%d callers closed the broadcast", firsts)
+	}
+	<-b.Done()
+	if _, ok := <-sub; ok {
+		t.Error("subscriber channel should be closed")
+	}
+}
+
+func TestBroadcastUnsubscribe(t *testing.T) {
+	leakcheck.Check(t)
+	b := NewBroadcast[int]()
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := b.Subscribe(ctx, 0)
+	active := b.Subscribe(context.Background(), 1)
+
+	// 没有人读取 gone，取消订阅后 Send 不再阻塞在它上面
+	sent := make(chan error, 1)
+	go func() { sent <- b.Send(context.Background(), 1) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-gone; ok {
+		t.Error("cancelled subscription should be closed without a value")
+	}
+	if v := <-active; v != 1 {
+		t.Errorf("got %d", v)
+	}
+
+	t.Run("slow subscriber does not block Close", func(t *testing.T) {
+		b := NewBroadcast[int]()
+		b.Subscribe(context.Background(), 0)
+		sent := make(chan error, 1)
+		go func() { sent <- b.Send(context.Background(), 1) }()
+		time.Sleep(10 * time.Millisecond)
+		b.Close()
+		if err := <-sent; !errors.Is(err, BroadcastClosedError) {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("send timeout", func(t *testing.T) {
+		b := NewBroadcast[int]()
+		defer b.Close()
+		b.Subscribe(context.Background(), 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := b.Send(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v", err)
+		}
+	})
+}
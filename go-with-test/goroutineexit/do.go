@@ -55,7 +55,7 @@ func safeClose(ch chan int) (justClosed bool) {
 	return
 }
 
-// 礼貌的方式
+// 礼貌的方式，但 once 是包级变量，只能用于一个信道，可以重复关闭的信道见 Broadcast
 var once sync.Once
 
 func myclose(ch chan int) {
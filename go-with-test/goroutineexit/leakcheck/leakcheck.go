@@ -0,0 +1,93 @@
+// Package leakcheck 检查测试结束后是否有遗留的 goroutine，思路与 go.uber.org/goleak 相同：
+// 比较测试开始和结束时的 goroutine，新出现且一段时间内没有退出的视为泄漏。
+// 依赖全局的 goroutine 列表，不能用于调用了 t.Parallel 的测试。
+package leakcheck
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timeout 等待 goroutine 退出的最长时间
+var Timeout = time.Second
+
+// Check 记录当前的 goroutine，在测试结束时报告新出现的
+func Check(tb testing.TB) {
+	tb.Helper()
+	before := goroutines()
+	tb.Cleanup(func() {
+		if leaked := Leaked(before, Timeout); len(leaked) > 0 {
+			tb.Errorf("found %d leaked goroutines:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// Leaked 在 timeout 内反复检查，返回不在 before 中的 goroutine 的调用栈
+func Leaked(before map[int]string, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	wait := time.Millisecond
+	for {
+		var leaked []string
+		for id, stack := range goroutines() {
+			if _, ok := before[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(wait)
+		wait = min(2*wait, 100*time.Millisecond)
+	}
+}
+
+// Snapshot 返回当前所有 goroutine 的 ID，与 Leaked 配合使用
+func Snapshot() map[int]string {
+	return goroutines()
+}
+
+// goroutines 解析 runtime.Stack 的输出，每段以 "goroutine N [state]:" 开头
+func goroutines() map[int]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	current := currentID()
+	result := make(map[int]string)
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		header, _, _ := strings.Cut(string(block), "\n")
+		id, ok := parseID(header)
+		if !ok || id == current {
+			continue
+		}
+		result[id] = string(block)
+	}
+	return result
+}
+
+func currentID() int {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	id, _ := parseID(string(buf))
+	return id
+}
+
+func parseID(header string) (int, bool) {
+	rest, ok := strings.CutPrefix(header, "goroutine ")
+	if !ok {
+		return 0, false
+	}
+	idText, _, _ := strings.Cut(rest, " ")
+	id, err := strconv.Atoi(idText)
+	return id, err == nil
+}
@@ -0,0 +1,36 @@
+package leakcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaked(t *testing.T) {
+	before := Snapshot()
+	stop := make(chan struct{})
+	go blockForever(stop)
+
+	leaked := Leaked(before, 50*time.Millisecond)
+	if len(leaked) != 1 || !strings.Contains(leaked[0], "blockForever") {
+		t.Fatalf("got %q", leaked)
+	}
+
+	close(stop)
+	if leaked := Leaked(before, time.Second); len(leaked) != 0 {
+		t.Errorf("goroutine that exits within the timeout is not a leak: %q", leaked)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	Check(t)
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(done)
+	}()
+}
+
+func blockForever(stop chan struct{}) {
+	<-stop
+}
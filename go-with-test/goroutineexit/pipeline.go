@@ -0,0 +1,120 @@
+package goroutineexit
+
+import (
+	"context"
+	"sync"
+)
+
+// 以下的管道函数都遵守同样的约定：输出信道由函数内部的 goroutine 关闭，
+// 输入信道关闭或者 ctx 被取消时 goroutine 退出，调用方不需要读完输出信道。
+
+// OrDone 转发 in 中的值，ctx 被取消时停止
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FanIn 把多个信道合并为一个，所有输入都关闭后关闭输出
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func() {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// FanOut 启动 n 个 goroutine 竞争读取 in，每个值只会出现在其中一个输出中，谁空闲谁处理
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, n)
+	for i := range n {
+		out := make(chan T)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			for v := range OrDone(ctx, in) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	return outs
+}
+
+// Tee 把 in 中的每个值都发送到两个输出，两个输出都收到后才读取下一个值
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// 已经发送过的一方置为 nil，阻塞在另一方上
+			o1, o2 := out1, out2
+			for range 2 {
+				select {
+				case <-ctx.Done():
+					return
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge 依次读完 chans 中的每个信道，把信道的信道展开为一个信道
+func Bridge[T any](ctx context.Context, chans <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for in := range OrDone(ctx, chans) {
+			for v := range OrDone(ctx, in) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
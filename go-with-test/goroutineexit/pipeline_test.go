@@ -0,0 +1,141 @@
+package goroutineexit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"goroutineexit/leakcheck"
+)
+
+func generate(ctx context.Context, values ...int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func collect(in <-chan int) []int {
+	var got []int
+	for v := range in {
+		got = append(got, v)
+	}
+	slices.Sort(got)
+	return got
+}
+
+func TestFanIn(t *testing.T) {
+	leakcheck.Check(t)
+	ctx := context.Background()
+	got := collect(FanIn(ctx, generate(ctx, 1, 4), generate(ctx, 2), generate(ctx, 3, 5)))
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	leakcheck.Check(t)
+	ctx := context.Background()
+	outs := FanOut(ctx, generate(ctx, 1, 2, 3, 4, 5, 6), 3)
+	if len(outs) != 3 {
+		t.Fatalf("got %d outputs", len(outs))
+	}
+
+	var mu sync.Mutex
+	var all []int
+	var wg sync.WaitGroup
+	for _, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got := collect(out)
+			mu.Lock()
+			all = append(all, got...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	slices.Sort(all)
+	if !slices.Equal(all, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("every value should be delivered exactly once, got %v", all)
+	}
+}
+
+func TestTee(t *testing.T) {
+	leakcheck.Check(t)
+	ctx := context.Background()
+	a, b := Tee(ctx, generate(ctx, 1, 2, 3))
+
+	var got1, got2 []int
+	for a != nil || b != nil {
+		select {
+		case v, ok := <-a:
+			if !ok {
+				a = nil
+				continue
+			}
+			got1 = append(got1, v)
+		case v, ok := <-b:
+			if !ok {
+				b = nil
+				continue
+			}
+			got2 = append(got2, v)
+		}
+	}
+	if !slices.Equal(got1, []int{1, 2, 3}) || !slices.Equal(got2, []int{1, 2, 3}) {
+		t.Errorf("got %v and %v", got1, got2)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	leakcheck.Check(t)
+	ctx := context.Background()
+	chans := make(chan (<-chan int))
+	go func() {
+		defer close(chans)
+		for i := range 3 {
+			chans <- generate(ctx, i*10, i*10+1)
+		}
+	}()
+
+	var got []int
+	for v := range Bridge(ctx, chans) {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{0, 1, 10, 11, 20, 21}) {
+		t.Errorf("values should keep the order of the channels, got %v", got)
+	}
+}
+
+// 调用方中途停止读取时，取消 ctx 后所有 goroutine 都应该退出
+func TestPipelineCancel(t *testing.T) {
+	leakcheck.Check(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	endless := make(chan int)
+	go func() {
+		defer close(endless)
+		for i := 0; send(ctx, endless, i); i++ {
+		}
+	}()
+
+	a, b := Tee(ctx, endless)
+	outs := FanOut(ctx, FanIn(ctx, a, b), 2)
+	chans := make(chan (<-chan int), 1)
+	chans <- outs[0]
+	close(chans)
+	bridged := Bridge(ctx, chans)
+
+	<-bridged
+	<-outs[1]
+	cancel()
+}
@@ -0,0 +1,296 @@
+package gortimeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerOpenError 熔断器处于打开状态，或者半开状态下探测请求已满，调用被直接拒绝
+var BreakerOpenError = errors.New("circuit breaker is open")
+
+// Clock 熔断器使用的时钟，测试中替换为可以手动拨动的假时钟
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	// StateClosed 正常放行，统计失败率
+	StateClosed BreakerState = iota
+	// StateOpen 直接拒绝，OpenTimeout 之后进入半开
+	StateOpen
+	// StateHalfOpen 放行少量探测请求，全部成功则关闭，任意一个失败则重新打开
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// StateChange 熔断器状态变化事件
+type StateChange struct {
+	Name     string
+	From, To BreakerState
+	At       time.Time
+}
+
+// BreakerSettings 熔断器配置，零值字段使用括号中的默认值
+type BreakerSettings struct {
+	// Name 下游依赖的名字，出现在事件和错误中
+	Name string
+
+	// Window 统计失败率的滚动窗口 (10s)，划分为 Buckets 个桶 (10)，过期的桶整体丢弃。
+	// 每个桶至少 1ns，Buckets 超过 Window 的纳秒数时减少为 Window 的纳秒数
+	Window  time.Duration
+	Buckets int
+	// MinRequests 窗口内请求数达到该值才会按失败率熔断 (10)，避免少量请求时误判
+	MinRequests int
+	// FailureRatio 窗口内失败率达到该值时熔断 (0.5)
+	FailureRatio float64
+	// ConsecutiveFailures 连续失败达到该值时立即熔断，0 表示不启用
+	ConsecutiveFailures int
+
+	// OpenTimeout 打开状态持续的时间 (30s)
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls 半开状态下允许的探测请求数 (1)，全部成功后关闭
+	HalfOpenMaxCalls int
+
+	// IsFailure 判断一次调用是否算作失败 (err != nil 且不是调用方取消)
+	IsFailure func(error) bool
+	// OnStateChange 状态变化时同步调用，不持有熔断器的锁
+	OnStateChange func(StateChange)
+	Clock         Clock
+}
+
+type bucket struct {
+	successes, failures int
+}
+
+// Breaker 熔断器：下游持续失败时快速失败，给下游恢复的时间，而不是让所有调用方继续等待超时
+type Breaker struct {
+	settings BreakerSettings
+	width    time.Duration
+
+	mu      sync.Mutex
+	state   BreakerState
+	openAt  time.Time
+	buckets []bucket
+	head    int
+	// headStart 当前桶的起始时间
+	headStart   time.Time
+	consecutive int
+	// probes 半开状态下已放行的探测数，passed 是其中已成功的
+	probes, passed int
+	// generation 每次状态变化加一，旧状态下发出的请求结果直接丢弃
+	generation uint64
+}
+
+// NewBreaker 创建熔断器，初始状态为关闭
+func NewBreaker(settings BreakerSettings) *Breaker {
+	if settings.Window <= 0 {
+		settings.Window = 10 * time.Second
+	}
+	if settings.Buckets <= 0 {
+		settings.Buckets = 10
+	}
+	if time.Duration(settings.Buckets) > settings.Window {
+		// 否则桶的宽度为 0，advance 会除以 0
+		settings.Buckets = int(settings.Window)
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = 10
+	}
+	if settings.FailureRatio <= 0 {
+		settings.FailureRatio = 0.5
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = 30 * time.Second
+	}
+	if settings.HalfOpenMaxCalls <= 0 {
+		settings.HalfOpenMaxCalls = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	if settings.Clock == nil {
+		settings.Clock = realClock{}
+	}
+	return &Breaker{
+		settings: settings,
+		width:    settings.Window / time.Duration(settings.Buckets),
+		buckets:  make([]bucket, settings.Buckets),
+	}
+}
+
+// State 返回当前状态，打开超时后会返回半开
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	state, change := b.currentState(b.settings.Clock.Now())
+	b.mu.Unlock()
+	b.emit(change)
+	return state
+}
+
+// Counts 返回滚动窗口内的成功与失败次数
+func (b *Breaker) Counts() (successes, failures int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.settings.Clock.Now())
+	for _, bk := range b.buckets {
+		successes += bk.successes
+		failures += bk.failures
+	}
+	return successes, failures
+}
+
+// Allow 判断是否放行一次调用。放行时返回的 done 必须在调用结束后以调用的错误调用一次。
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	now := b.settings.Clock.Now()
+	state, change := b.currentState(now)
+	switch state {
+	case StateOpen:
+		err = fmt.Errorf("%w: %s", BreakerOpenError, b.settings.Name)
+	case StateHalfOpen:
+		if b.probes >= b.settings.HalfOpenMaxCalls {
+			err = fmt.Errorf("%w: %s is half-open", BreakerOpenError, b.settings.Name)
+		} else {
+			b.probes++
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.emit(change)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(generation, err) })
+	}, nil
+}
+
+// Execute 在熔断器保护下调用 fn。与 Do 组合时熔断器放在里面，打开后 Do 不会继续重试：
+//
+//	Do(ctx, func(ctx context.Context) (T, error) { return Execute(ctx, breaker, fn) }, policy)
+func Execute[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	done, err := b.Allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	v, err := fn(ctx)
+	done(err)
+	return v, err
+}
+
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	now := b.settings.Clock.Now()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	var change *StateChange
+	failed := b.settings.IsFailure(err)
+	switch b.state {
+	case StateClosed:
+		b.advance(now)
+		if failed {
+			b.buckets[b.head].failures++
+			b.consecutive++
+		} else {
+			b.buckets[b.head].successes++
+			b.consecutive = 0
+		}
+		if failed && b.shouldTrip() {
+			change = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failed {
+			change = b.setState(StateOpen, now)
+		} else if b.passed++; b.passed >= b.settings.HalfOpenMaxCalls {
+			change = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	b.emit(change)
+}
+
+func (b *Breaker) shouldTrip() bool {
+	if n := b.settings.ConsecutiveFailures; n > 0 && b.consecutive >= n {
+		return true
+	}
+	var total, failures int
+	for _, bk := range b.buckets {
+		total += bk.successes + bk.failures
+		failures += bk.failures
+	}
+	return total >= b.settings.MinRequests && float64(failures)/float64(total) >= b.settings.FailureRatio
+}
+
+// currentState 打开状态超时后切换到半开，调用方需持有 b.mu
+func (b *Breaker) currentState(now time.Time) (BreakerState, *StateChange) {
+	if b.state == StateOpen && !now.Before(b.openAt.Add(b.settings.OpenTimeout)) {
+		return StateHalfOpen, b.setState(StateHalfOpen, now)
+	}
+	return b.state, nil
+}
+
+// setState 切换状态并清空统计，调用方需持有 b.mu
+func (b *Breaker) setState(to BreakerState, now time.Time) *StateChange {
+	change := &StateChange{Name: b.settings.Name, From: b.state, To: to, At: now}
+	b.state = to
+	b.generation++
+	b.probes, b.passed = 0, 0
+	b.consecutive = 0
+	clear(b.buckets)
+	if to == StateOpen {
+		b.openAt = now
+	}
+	return change
+}
+
+// advance 把滚动窗口推进到 now，过期的桶清零，调用方需持有 b.mu
+func (b *Breaker) advance(now time.Time) {
+	if b.headStart.IsZero() {
+		b.headStart = now
+		return
+	}
+	steps := int(now.Sub(b.headStart) / b.width)
+	if steps <= 0 {
+		return
+	}
+	b.headStart = b.headStart.Add(time.Duration(steps) * b.width)
+	for i := 0; i < min(steps, len(b.buckets)); i++ {
+		b.head = (b.head + 1) % len(b.buckets)
+		b.buckets[b.head] = bucket{}
+	}
+}
+
+func (b *Breaker) emit(change *StateChange) {
+	if change != nil && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(*change)
+	}
+}
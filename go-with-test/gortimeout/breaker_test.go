@@ -0,0 +1,262 @@
+package gortimeout
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// fakeClock 只有调用 Advance 时才会走动
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(clock Clock, events *[]StateChange) *Breaker {
+	return NewBreaker(BreakerSettings{
+		Name:             "inventory",
+		Window:           10 * time.Second,
+		Buckets:          10,
+		MinRequests:      4,
+		FailureRatio:     0.5,
+		OpenTimeout:      5 * time.Second,
+		HalfOpenMaxCalls: 2,
+		Clock:            clock,
+		OnStateChange: func(change StateChange) {
+			*events = append(*events, change)
+		},
+	})
+}
+
+func call(b *Breaker, err error) error {
+	_, callErr := Execute(context.Background(), b, func(ctx context.Context) (int, error) {
+		return 0, err
+	})
+	return callErr
+}
+
+func TestBreaker(t *testing.T) {
+	clock := newFakeClock()
+	var events []StateChange
+	b := newTestBreaker(clock, &events)
+
+	// 请求数不足 MinRequests 时不熔断
+	require.ErrorIs(t, call(b, unavailableError), unavailableError)
+	require.ErrorIs(t, call(b, unavailableError), unavailableError)
+	assert.Equal(t, StateClosed, b.State())
+
+	require.NoError(t, call(b, nil))
+	require.ErrorIs(t, call(b, unavailableError), unavailableError)
+	assert.Equal(t, StateOpen, b.State(), "3 of 4 calls failed")
+
+	err := call(b, nil)
+	assert.ErrorIs(t, err, BreakerOpenError)
+	assert.ErrorContains(t, err, "inventory")
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// 半开状态只放行 HalfOpenMaxCalls 个探测
+	done1, err := b.Allow()
+	require.NoError(t, err)
+	done2, err := b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, BreakerOpenError)
+
+	done1(nil)
+	assert.Equal(t, StateHalfOpen, b.State())
+	done2(nil)
+	assert.Equal(t, StateClosed, b.State())
+
+	require.Len(t, events, 3)
+	assert.Equal(t, StateChange{Name: "inventory", From: StateClosed, To: StateOpen, At: clock.Now().Add(-5 * time.Second)}, events[0])
+	assert.Equal(t, StateOpen, events[1].From)
+	assert.Equal(t, StateHalfOpen, events[1].To)
+	assert.Equal(t, StateClosed, events[2].To)
+
+	t.Run("failed probe reopens", func(t *testing.T) {
+		clock := newFakeClock()
+		var events []StateChange
+		b := newTestBreaker(clock, &events)
+		for i := 0; i < 4; i++ {
+			_ = call(b, unavailableError)
+		}
+		clock.Advance(5 * time.Second)
+		require.ErrorIs(t, call(b, unavailableError), unavailableError)
+		assert.Equal(t, StateOpen, b.State())
+
+		clock.Advance(4 * time.Second)
+		assert.Equal(t, StateOpen, b.State(), "open timeout restarts from the failed probe")
+	})
+}
+
+func TestBreakerRollingWindow(t *testing.T) {
+	clock := newFakeClock()
+	var events []StateChange
+	b := newTestBreaker(clock, &events)
+
+	for i := 0; i < 3; i++ {
+		_ = call(b, unavailableError)
+	}
+	// 旧的失败滑出窗口
+	clock.Advance(11 * time.Second)
+	_, failures := b.Counts()
+	assert.Equal(t, 0, failures)
+
+	for i := 0; i < 3; i++ {
+		_ = call(b, nil)
+	}
+	_ = call(b, unavailableError)
+	assert.Equal(t, StateClosed, b.State())
+
+	// 窗口内一部分桶过期
+	clock.Advance(6 * time.Second)
+	_ = call(b, unavailableError)
+	successes, failures := b.Counts()
+	assert.Equal(t, 3, successes)
+	assert.Equal(t, 2, failures)
+	assert.Equal(t, StateClosed, b.State(), "2 of 5 calls failed")
+
+	clock.Advance(5 * time.Second)
+	successes, failures = b.Counts()
+	assert.Equal(t, 0, successes)
+	assert.Equal(t, 1, failures)
+}
+
+func TestBreakerTinyWindow(t *testing.T) {
+	clock := newFakeClock()
+	b := NewBreaker(BreakerSettings{Window: 5 * time.Nanosecond, Buckets: 10, Clock: clock})
+	assert.Equal(t, 5, b.settings.Buckets)
+
+	_ = call(b, unavailableError)
+	clock.Advance(3 * time.Nanosecond)
+	_ = call(b, unavailableError)
+	_, failures := b.Counts()
+	assert.Equal(t, 2, failures)
+
+	clock.Advance(10 * time.Nanosecond)
+	_, failures = b.Counts()
+	assert.Equal(t, 0, failures)
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	b := NewBreaker(BreakerSettings{ConsecutiveFailures: 3, Clock: newFakeClock()})
+	for i := 0; i < 20; i++ {
+		_ = call(b, nil)
+	}
+	_ = call(b, unavailableError)
+	_ = call(b, unavailableError)
+	assert.Equal(t, StateClosed, b.State())
+	_ = call(b, unavailableError)
+	assert.Equal(t, StateOpen, b.State())
+
+	t.Run("cancellation is not a failure", func(t *testing.T) {
+		b := NewBreaker(BreakerSettings{ConsecutiveFailures: 1, Clock: newFakeClock()})
+		_ = call(b, context.Canceled)
+		assert.Equal(t, StateClosed, b.State())
+	})
+
+	t.Run("results from an earlier state are ignored", func(t *testing.T) {
+		b := NewBreaker(BreakerSettings{ConsecutiveFailures: 1, Clock: newFakeClock()})
+		slowDone, err := b.Allow()
+		require.NoError(t, err)
+		_ = call(b, unavailableError)
+		require.Equal(t, StateOpen, b.State())
+		slowDone(nil)
+		assert.Equal(t, StateOpen, b.State())
+	})
+}
+
+func TestBreakerWithRetry(t *testing.T) {
+	b := NewBreaker(BreakerSettings{ConsecutiveFailures: 2})
+	fn, calls := flaky(100)
+
+	_, err := Do(context.Background(), func(ctx context.Context) (int32, error) {
+		return Execute(ctx, b, fn)
+	}, Policy{MaxAttempts: 10, InitialBackoff: time.Millisecond})
+	assert.ErrorIs(t, err, BreakerOpenError)
+	assert.Equal(t, int32(2), calls.Load(), "Do stops retrying once the breaker opens")
+}
+
+func TestBulkhead(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	b := NewBulkhead("inventory", 2, 0)
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = Isolate(ctx, b, func(ctx context.Context) (int, error) {
+				<-release
+				return 0, nil
+			})
+		}()
+	}
+	require.Eventually(t, func() bool { return b.InFlight() == 2 }, time.Second, time.Millisecond)
+
+	_, err := Isolate(ctx, b, func(ctx context.Context) (int, error) { return 0, nil })
+	assert.ErrorIs(t, err, BulkheadFullError)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 0, b.InFlight())
+
+	t.Run("waits for a free slot", func(t *testing.T) {
+		b := NewBulkhead("inventory", 1, time.Second)
+		first, err := b.Acquire(ctx)
+		require.NoError(t, err)
+		time.AfterFunc(10*time.Millisecond, first)
+
+		var ran atomic.Bool
+		err = Retry(ctx, func(ctx context.Context) error {
+			_, err := Isolate(ctx, b, func(ctx context.Context) (int, error) {
+				ran.Store(true)
+				return 0, nil
+			})
+			return err
+		}, Policy{})
+		require.NoError(t, err)
+		assert.True(t, ran.Load())
+	})
+
+	t.Run("gives up after max wait or cancellation", func(t *testing.T) {
+		b := NewBulkhead("inventory", 1, 20*time.Millisecond)
+		release, err := b.Acquire(ctx)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = b.Acquire(ctx)
+		assert.ErrorIs(t, err, BulkheadFullError)
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = b.Acquire(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,69 @@
+package gortimeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BulkheadFullError 并发调用数已满，在等待时间内没有空出位置
+var BulkheadFullError = errors.New("bulkhead is full")
+
+// Bulkhead 舱壁：限制对某个下游的并发调用数，一个下游变慢时不会占满调用方所有的 goroutine 和连接。
+// 每个下游依赖使用各自的 Bulkhead。
+type Bulkhead struct {
+	name    string
+	sem     chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead 最多允许 maxConcurrent 个并发调用，已满时最多等待 maxWait，0 表示不等待直接拒绝
+func NewBulkhead(name string, maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	return &Bulkhead{
+		name:    name,
+		sem:     make(chan struct{}, max(maxConcurrent, 1)),
+		maxWait: maxWait,
+	}
+}
+
+// InFlight 当前正在进行的调用数
+func (b *Bulkhead) InFlight() int {
+	return len(b.sem)
+}
+
+// Acquire 占用一个位置，成功时返回的 release 必须调用一次
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-b.sem }
+
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if b.maxWait <= 0 {
+		return nil, fmt.Errorf("%w: %s", BulkheadFullError, b.name)
+	}
+
+	t := time.NewTimer(b.maxWait)
+	defer t.Stop()
+	select {
+	case b.sem <- struct{}{}:
+		return release, nil
+	case <-t.C:
+		return nil, fmt.Errorf("%w: %s, waited %s", BulkheadFullError, b.name, b.maxWait)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Isolate 在舱壁内调用 fn
+func Isolate[T any](ctx context.Context, b *Bulkhead, fn func(ctx context.Context) (T, error)) (T, error) {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return fn(ctx)
+}
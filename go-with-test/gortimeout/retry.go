@@ -0,0 +1,201 @@
+package gortimeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// AttemptTimeoutError 单次尝试超过了 Policy.AttemptTimeout，默认可以重试
+var AttemptTimeoutError = errors.New("attempt timed out")
+
+// Policy 描述 Do 的超时与重试策略，零值表示只尝试一次且不设超时
+type Policy struct {
+	// MaxAttempts 最多尝试的次数，小于 1 时按 1 处理
+	MaxAttempts int
+	// AttemptTimeout 单次尝试的超时时间，0 表示不限制
+	AttemptTimeout time.Duration
+	// Timeout 包括所有尝试与等待在内的总超时时间，0 表示只受调用方 ctx 限制
+	Timeout time.Duration
+
+	// InitialBackoff 第一次重试前的等待时间，之后每次乘以 Multiplier，最多 MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier 小于 1 时按 2 处理
+	Multiplier float64
+	// Jitter 等待时间的随机浮动比例，取值 [0, 1]，例如 0.2 表示在 ±20% 内随机，避免大量调用方同时重试
+	Jitter float64
+
+	// Retryable 判断错误是否值得重试，nil 表示都重试。
+	// Permanent 错误、熔断器打开和舱壁已满时总是不重试，重试只会加重下游的负担。
+	Retryable func(error) bool
+}
+
+// DefaultPolicy 最多 3 次，每次 1s 超时，重试间隔从 100ms 开始翻倍并带 ±20% 抖动
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		AttemptTimeout: time.Second,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff 返回第 retry 次重试（从 1 开始）之前的等待时间
+func (p Policy) Backoff(retry int) time.Duration {
+	if p.InitialBackoff <= 0 || retry < 1 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= multiplier
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d *= 1 - jitter + 2*jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+func (p Policy) retryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) || errors.Is(err, BreakerOpenError) || errors.Is(err, BulkheadFullError) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装一个不应重试的错误，例如参数错误，Do 遇到它会立即返回
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do 按 policy 调用 fn 直到成功、遇到不可重试的错误、次数用完或者 ctx 结束。
+// fn 应当在其 ctx 结束时尽快返回：单次尝试超时后 Do 不再等待它，但结果信道有缓冲区，
+// fn 返回后 goroutine 随即退出，不会像 doSomething 那样永远阻塞。
+func Do[T any](ctx context.Context, fn func(ctx context.Context) (T, error), policy Policy) (T, error) {
+	var zero T
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+	attempts := max(policy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		v, err := doAttempt(ctx, fn, policy.AttemptTimeout)
+		if err == nil {
+			return v, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return zero, fmt.Errorf("%w after %d attempts: %w", ctx.Err(), attempt, lastErr)
+		}
+		if !policy.retryable(err) {
+			return zero, err
+		}
+		if attempt >= attempts {
+			return zero, fmt.Errorf("gave up after %d attempts: %w", attempt, lastErr)
+		}
+
+		wait := policy.Backoff(attempt)
+		// 等待结束前就会超过总的截止时间，没必要再等
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return zero, fmt.Errorf("%w after %d attempts: %w", context.DeadlineExceeded, attempt, lastErr)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return zero, fmt.Errorf("%w after %d attempts: %w", err, attempt, lastErr)
+		}
+	}
+}
+
+// Retry 同 Do，用于没有返回值的操作
+func Retry(ctx context.Context, fn func(ctx context.Context) error, policy Policy) error {
+	_, err := Do(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, policy)
+	return err
+}
+
+type result[T any] struct {
+	v   T
+	err error
+}
+
+func doAttempt[T any](ctx context.Context, fn func(ctx context.Context) (T, error), timeout time.Duration) (T, error) {
+	var (
+		attemptCtx context.Context
+		cancel     context.CancelFunc
+	)
+	if timeout > 0 {
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		attemptCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// 与 timeout 一样缓冲区设置为 1，超时后没有接收方，发送也不会阻塞
+	done := make(chan result[T], 1)
+	go func() {
+		v, err := fn(attemptCtx)
+		done <- result[T]{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-attemptCtx.Done():
+		var zero T
+		// 调用方的 ctx 先结束时返回它的错误，否则是这一次尝试超时
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("%w after %s", AttemptTimeoutError, timeout)
+	}
+}
+
+// sleep 等待 d 或者 ctx 结束，用 time.NewTimer 而不是 time.After，提前返回时定时器会被及时回收
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,177 @@
+package gortimeout
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+var unavailableError = errors.New("service unavailable")
+
+// flaky 前 failures 次返回 unavailableError，之后返回 attempt 次数
+func flaky(failures int32) (func(ctx context.Context) (int32, error), *atomic.Int32) {
+	var calls atomic.Int32
+	return func(ctx context.Context) (int32, error) {
+		n := calls.Add(1)
+		if n <= failures {
+			return 0, unavailableError
+		}
+		return n, nil
+	}, &calls
+}
+
+// slow 模拟响应 ctx 的慢操作，与 doSomethingGood 一样不会在超时后阻塞
+func slow(d time.Duration) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(d):
+			return "done", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func fastPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		AttemptTimeout: 50 * time.Millisecond,
+		InitialBackoff: time.Millisecond,
+		Multiplier:     2,
+	}
+}
+
+func TestDo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+
+	t.Run("retries until success", func(t *testing.T) {
+		fn, calls := flaky(2)
+		v, err := Do(ctx, fn, fastPolicy())
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), v)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		fn, calls := flaky(5)
+		_, err := Do(ctx, fn, fastPolicy())
+		assert.ErrorIs(t, err, unavailableError)
+		assert.ErrorContains(t, err, "gave up after 3 attempts")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("zero policy tries once", func(t *testing.T) {
+		fn, calls := flaky(1)
+		_, err := Do(ctx, fn, Policy{})
+		assert.ErrorIs(t, err, unavailableError)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		invalid := errors.New("invalid argument")
+		err := Retry(ctx, func(ctx context.Context) error {
+			calls.Add(1)
+			return Permanent(invalid)
+		}, fastPolicy())
+		assert.ErrorIs(t, err, invalid)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("custom retryable classification", func(t *testing.T) {
+		policy := fastPolicy()
+		policy.Retryable = func(err error) bool { return !errors.Is(err, unavailableError) }
+		fn, calls := flaky(5)
+		_, err := Do(ctx, fn, policy)
+		assert.ErrorIs(t, err, unavailableError)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("attempt timeout is retried", func(t *testing.T) {
+		var calls atomic.Int32
+		v, err := Do(ctx, func(ctx context.Context) (string, error) {
+			if calls.Add(1) == 1 {
+				return slow(time.Second)(ctx)
+			}
+			return "ok", nil
+		}, fastPolicy())
+		require.NoError(t, err)
+		assert.Equal(t, "ok", v)
+		assert.Equal(t, int32(2), calls.Load())
+
+		_, err = Do(ctx, slow(time.Second), fastPolicy())
+		assert.ErrorIs(t, err, AttemptTimeoutError)
+	})
+
+	t.Run("overall timeout", func(t *testing.T) {
+		policy := fastPolicy()
+		policy.MaxAttempts = 100
+		policy.Timeout = 120 * time.Millisecond
+
+		begin := time.Now()
+		_, err := Do(ctx, slow(time.Second), policy)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(begin), 500*time.Millisecond)
+	})
+
+	t.Run("caller cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		policy := fastPolicy()
+		policy.AttemptTimeout = 0
+		_, err := Do(ctx, slow(time.Second), policy)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, AttemptTimeoutError)
+	})
+
+	t.Run("stops early when backoff exceeds deadline", func(t *testing.T) {
+		policy := fastPolicy()
+		policy.InitialBackoff = time.Minute
+		policy.Timeout = time.Second
+		fn, calls := flaky(5)
+
+		begin := time.Now()
+		_, err := Do(ctx, fn, policy)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, err, unavailableError)
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Less(t, time.Since(begin), 100*time.Millisecond)
+	})
+}
+
+func TestDoNoLeak(t *testing.T) {
+	// 与 TestTimeout 相同的压力，但用 goleak 确认调用返回后没有遗留的 goroutine
+	defer goleak.VerifyNone(t)
+
+	policy := Policy{MaxAttempts: 2, AttemptTimeout: time.Millisecond}
+	for i := 0; i < 100; i++ {
+		_, _ = Do(context.Background(), slow(10*time.Millisecond), policy)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	assert.Equal(t, time.Duration(0), p.Backoff(0))
+	assert.Equal(t, 100*time.Millisecond, p.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, p.Backoff(4))
+	assert.Equal(t, time.Second, p.Backoff(5))
+	assert.Equal(t, time.Second, p.Backoff(1000))
+
+	p.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(2)
+		assert.GreaterOrEqual(t, d, 160*time.Millisecond)
+		assert.LessOrEqual(t, d, 240*time.Millisecond)
+	}
+
+	assert.Equal(t, time.Duration(0), Policy{}.Backoff(3))
+}
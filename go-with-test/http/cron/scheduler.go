@@ -0,0 +1,242 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	JobExistError    = errors.New("job already exists")
+	JobNotFoundError = errors.New("job not found")
+)
+
+// Job 定时执行的任务
+type Job interface {
+	Run(ctx context.Context) error
+}
+
+// JobFunc 把函数适配为 Job
+type JobFunc func(ctx context.Context) error
+
+func (f JobFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Entry 任务的调度状态
+type Entry struct {
+	Name     string
+	Schedule Schedule
+	// Prev 上一次开始执行的时间，Next 下一次执行的时间，零值表示不会再执行
+	Prev time.Time
+	Next time.Time
+	// LastDuration、LastErr 上一次执行完成的耗时和结果
+	LastDuration time.Duration
+	LastErr      error
+	Running      bool
+	Runs         int
+	// Skipped 到点时上一次还没有执行完而跳过的次数，同一个任务不会重叠执行
+	Skipped int
+}
+
+type entry struct {
+	Entry
+	job Job
+}
+
+// Scheduler 按 Schedule 执行任务，零值可以直接使用，与 NewScheduler 返回的相同
+type Scheduler struct {
+	// Clock 为 nil 时使用真实时间
+	Clock Clock
+	// Location 解析没有指定时区的 cron 表达式时使用，为 nil 时使用 time.Local
+	Location *time.Location
+	// OnError 任务返回错误时调用，默认写日志
+	OnError func(name string, err error)
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// wake 由 wakeup 创建
+	wake     chan struct{}
+	wakeOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewScheduler 返回使用真实时间和本地时区的调度器
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		Clock:    SystemClock,
+		Location: time.Local,
+		entries:  make(map[string]*entry),
+	}
+}
+
+// Add 按 cron 表达式添加任务，表达式的格式见 ParseInLocation
+func (s *Scheduler) Add(name, spec string, job Job) error {
+	schedule, err := ParseInLocation(spec, s.Location)
+	if err != nil {
+		return err
+	}
+	return s.AddSchedule(name, schedule, job)
+}
+
+// AddSchedule 添加任务，任务名不能重复
+func (s *Scheduler) AddSchedule(name string, schedule Schedule, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("%w: %s", JobExistError, name)
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]*entry)
+	}
+	s.entries[name] = &entry{
+		Entry: Entry{Name: name, Schedule: schedule, Next: schedule.Next(s.clock().Now())},
+		job:   job,
+	}
+	s.notify()
+	return nil
+}
+
+// Remove 删除任务，正在执行的不会被中断
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; !ok {
+		return fmt.Errorf("%w: %s", JobNotFoundError, name)
+	}
+	delete(s.entries, name)
+	s.notify()
+	return nil
+}
+
+// Entry 返回任务 name 的调度状态
+func (s *Scheduler) Entry(name string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return Entry{}, false
+	}
+	return e.Entry, true
+}
+
+// Entries 返回所有任务的调度状态，按任务名排序
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e.Entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Run 执行到期的任务直到 ctx 被取消，任务收到的也是这个 ctx，返回前等待正在执行的任务结束。
+// 错过的执行时间（例如进程被挂起）不会补执行，醒来后只执行一次并从当前时间计算下一次。
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.wg.Wait()
+	for {
+		next := s.runDue(ctx, s.clock().Now())
+
+		var timer Timer
+		var fire <-chan time.Time
+		if !next.IsZero() {
+			timer = s.clock().NewTimer(next.Sub(s.clock().Now()))
+			fire = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+		case <-s.wakeup():
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runDue 启动所有到期的任务，返回最早的下一次执行时间
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, e := range s.entries {
+		if !e.Next.IsZero() && !e.Next.After(now) {
+			if e.Running {
+				e.Skipped++
+			} else {
+				s.start(ctx, e, now)
+			}
+			e.Next = e.Schedule.Next(now)
+		}
+		if !e.Next.IsZero() && (earliest.IsZero() || e.Next.Before(earliest)) {
+			earliest = e.Next
+		}
+	}
+	return earliest
+}
+
+// start 调用方需持有 s.mu
+func (s *Scheduler) start(ctx context.Context, e *entry, now time.Time) {
+	e.Running = true
+	e.Prev = now
+	e.Runs++
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := runSafely(ctx, e.job)
+
+		s.mu.Lock()
+		e.Running = false
+		e.LastDuration = s.clock().Now().Sub(now)
+		e.LastErr = err
+		s.mu.Unlock()
+
+		if err != nil {
+			if s.OnError != nil {
+				s.OnError(e.Name, err)
+			} else {
+				log.Printf("cron job %s: %v", e.Name, err)
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) clock() Clock {
+	if s.Clock == nil {
+		return SystemClock
+	}
+	return s.Clock
+}
+
+// wakeup 返回用于唤醒 Run 的 channel，零值的 Scheduler 在第一次使用时创建
+func (s *Scheduler) wakeup() chan struct{} {
+	s.wakeOnce.Do(func() {
+		s.wake = make(chan struct{}, 1)
+	})
+	return s.wake
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wakeup() <- struct{}{}:
+	default:
+	}
+}
+
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
@@ -0,0 +1,178 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T) (*Scheduler, *FakeClock, func()) {
+	t.Helper()
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewScheduler()
+	s.Clock = clock
+	s.Location = time.UTC
+	s.OnError = func(string, error) {}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := func() {
+		go func() {
+			s.Run(ctx)
+			close(done)
+		}()
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s, clock, start
+}
+
+func TestScheduler(t *testing.T) {
+	s, clock, start := newTestScheduler(t)
+	runs := make(chan time.Time, 10)
+	if err := s.Add("snapshot", "*/5 * * * *", JobFunc(func(ctx context.Context) error {
+		runs <- clock.Now()
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("snapshot", "@hourly", JobFunc(nil)); !errors.Is(err, JobExistError) {
+		t.Errorf("got %v", err)
+	}
+	if err := s.Add("bad", "* * *", JobFunc(nil)); !errors.Is(err, InvalidSpecError) {
+		t.Errorf("got %v", err)
+	}
+	start()
+
+	clock.BlockUntil(1)
+	clock.Advance(4 * time.Minute)
+	select {
+	case <-runs:
+		t.Fatal("ran too early")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	if at := <-runs; !at.Equal(time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)) {
+		t.Errorf("ran at %s", at)
+	}
+	clock.BlockUntil(1)
+	e, _ := s.Entry("snapshot")
+	if e.Runs != 1 || !e.Prev.Equal(time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)) || !e.Next.Equal(time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC)) {
+		t.Errorf("got %+v", e)
+	}
+
+	// 错过的执行不会补执行
+	clock.Advance(time.Hour)
+	<-runs
+	clock.BlockUntil(1)
+	if e, _ := s.Entry("snapshot"); e.Runs != 2 || !e.Next.Equal(time.Date(2024, 1, 1, 1, 10, 0, 0, time.UTC)) {
+		t.Errorf("got %+v", e)
+	}
+
+	if err := s.Remove("snapshot"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("snapshot"); !errors.Is(err, JobNotFoundError) {
+		t.Errorf("got %v", err)
+	}
+	if len(s.Entries()) != 0 {
+		t.Errorf("got %+v", s.Entries())
+	}
+}
+
+func TestSchedulerZeroValue(t *testing.T) {
+	var plain Scheduler
+	if err := plain.Add("report", "@hourly", JobFunc(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := plain.Entry("report"); !ok || e.Next.IsZero() {
+		t.Errorf("got %+v", e)
+	}
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Scheduler{Clock: clock}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Run 已经在等待时添加的任务也要唤醒它
+	runs := make(chan struct{}, 1)
+	if err := s.Add("tick", "@every 1m", JobFunc(func(ctx context.Context) error {
+		runs <- struct{}{}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-runs
+}
+
+func TestSchedulerNoOverlap(t *testing.T) {
+	s, clock, start := newTestScheduler(t)
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	s.AddSchedule("uptime", EverySchedule{Every: time.Minute}, JobFunc(func(ctx context.Context) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return errors.New("target down")
+	}))
+	start()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	e, _ := s.Entry("uptime")
+	if e.Runs != 1 || e.Skipped != 2 || !e.Running {
+		t.Errorf("got %+v", e)
+	}
+
+	close(release)
+	for {
+		if e, _ := s.Entry("uptime"); !e.Running {
+			if e.LastErr == nil || e.LastDuration != 2*time.Minute {
+				t.Errorf("got %+v", e)
+			}
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(time.Minute)
+	<-started
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	s, clock, start := newTestScheduler(t)
+	errs := make(chan error, 1)
+	s.OnError = func(name string, err error) { errs <- err }
+	s.Add("panic", "@every 1s", JobFunc(func(ctx context.Context) error {
+		panic("oops")
+	}))
+	start()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errs; err.Error() != "panic: oops" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+// Package cron 按 cron 表达式或固定间隔执行任务。时间由可替换的 Clock 驱动，
+// 测试中使用 FakeClock 手动拨动时间，不需要真的等待。
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var InvalidSpecError = errors.New("invalid cron spec")
+
+// Schedule 计算 t 之后的下一次执行时间，返回零值表示不会再执行
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// EverySchedule 固定间隔执行，即 @every 1m30s，与时区无关
+type EverySchedule struct {
+	Every time.Duration
+}
+
+func (s EverySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Every)
+}
+
+// star 字段写的是 * 或 ?，日期和星期只有一个限定时取交集，都限定时取并集，与标准 cron 相同
+const star = 1 << 63
+
+// SpecSchedule 解析后的 cron 表达式，每个字段是允许取值的位图
+type SpecSchedule struct {
+	Second, Minute, Hour, Dom, Month, Dow uint64
+	Location                              *time.Location
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds = bounds{0, 59, nil}
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 星期允许写 7 表示周日
+	dowBounds = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse 以本地时区解析 cron 表达式，见 ParseInLocation
+func Parse(spec string) (Schedule, error) {
+	return ParseInLocation(spec, time.Local)
+}
+
+// ParseInLocation 解析 cron 表达式，支持：
+//   - 5 个字段「分 时 日 月 周」，或在最前面加上秒的 6 个字段
+//   - 每个字段可以是 *、?、数字、月份和星期的英文缩写、范围 a-b、步长 */n 或 a-b/n，以及用逗号分隔的列表
+//   - @yearly、@monthly、@weekly、@daily、@hourly 以及 @every <duration>
+//   - 以 CRON_TZ=<时区> 或 TZ=<时区> 开头时使用该时区，否则使用 loc
+func ParseInLocation(spec string, loc *time.Location) (Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		name, rest, _ := strings.Cut(expr[strings.IndexByte(expr, '=')+1:], " ")
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", InvalidSpecError, spec, err)
+		}
+		expr = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q: interval must be a positive duration", InvalidSpecError, spec)
+		}
+		return EverySchedule{Every: d}, nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		expanded, ok := descriptors[expr]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: unknown descriptor", InvalidSpecError, spec)
+		}
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: %q: expected 5 or 6 fields, got %d", InvalidSpecError, spec, len(fields))
+	}
+
+	s := &SpecSchedule{Location: loc}
+	targets := []struct {
+		bits   *uint64
+		bounds bounds
+	}{
+		{&s.Second, secondBounds},
+		{&s.Minute, minuteBounds},
+		{&s.Hour, hourBounds},
+		{&s.Dom, domBounds},
+		{&s.Month, monthBounds},
+		{&s.Dow, dowBounds},
+	}
+	for i, target := range targets {
+		bits, err := parseField(fields[i], target.bounds)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", InvalidSpecError, spec, err)
+		}
+		*target.bits = bits
+	}
+	// 7 和 0 都表示周日
+	if s.Dow&(1<<7) != 0 {
+		s.Dow = s.Dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	if field == "*" || field == "?" {
+		return rangeBits(b.min, b.max, 1) | star, nil
+	}
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		lo, hi := b.min, b.max
+		if expr != "*" && expr != "?" {
+			first, last, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = parseValue(first, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			switch {
+			case isRange:
+				if hi, err = parseValue(last, b); err != nil {
+					return 0, err
+				}
+			case hasStep:
+				// a/n 表示从 a 开始到最大值
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		bits |= rangeBits(lo, hi, step)
+	}
+	return bits, nil
+}
+
+func parseValue(text string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+func rangeBits(lo, hi, step int) uint64 {
+	var bits uint64
+	for i := lo; i <= hi; i += step {
+		bits |= 1 << i
+	}
+	return bits
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<v) != 0
+}
+
+const allHours = 1<<24 - 1
+
+// Next 返回 t 之后第一个匹配的时间，精确到秒，5 年内没有匹配时返回零值。
+//
+// 夏令时切换时按墙上时间匹配：
+//   - 时钟拨快时被跳过的那段时间里的执行提前到切换的时刻执行一次
+//   - 时钟拨回时重复的那段时间，小时字段固定的任务只在第一次执行
+//
+// 小时字段为 * 的任务不做调整，拨快时跳过、拨回时重复，与按固定间隔执行的行为一致。
+func (s *SpecSchedule) Next(t time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	c := t.In(loc).Truncate(time.Second).Add(time.Second)
+	limit := c.AddDate(5, 0, 0)
+
+	for c.Before(limit) {
+		if s.skipped(c) {
+			return c
+		}
+		if !has(s.Month, int(c.Month())) {
+			c = time.Date(c.Year(), c.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(c.Day(), c.Weekday()) {
+			c = time.Date(c.Year(), c.Month(), c.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		sinceMinute := time.Duration(c.Second()) * time.Second
+		sinceHour := time.Duration(c.Minute())*time.Minute + sinceMinute
+		if !has(s.Hour, c.Hour()) {
+			c = c.Add(time.Hour - sinceHour)
+			continue
+		}
+		if !has(s.Minute, c.Minute()) {
+			c = c.Add(time.Minute - sinceMinute)
+			continue
+		}
+		if !has(s.Second, c.Second()) {
+			c = c.Add(time.Second)
+			continue
+		}
+		if end, ok := s.repeated(c); ok {
+			c = end
+			continue
+		}
+		return c
+	}
+	return time.Time{}
+}
+
+func (s *SpecSchedule) dayMatches(day int, weekday time.Weekday) bool {
+	dom, dow := has(s.Dom, day), has(s.Dow, int(weekday))
+	if s.Dom&star != 0 || s.Dow&star != 0 {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// wallMatches wall 是用 UTC 表示的墙上时间
+func (s *SpecSchedule) wallMatches(wall time.Time) bool {
+	return has(s.Month, int(wall.Month())) && s.dayMatches(wall.Day(), wall.Weekday()) &&
+		has(s.Hour, wall.Hour()) && has(s.Minute, wall.Minute()) && has(s.Second, wall.Second())
+}
+
+// offsetChange c 恰好是时区偏移变化的时刻时，返回变化前后的偏移之差（秒）
+func offsetChange(c time.Time) (start time.Time, change int) {
+	start, _ = c.ZoneBounds()
+	if start.IsZero() {
+		return start, 0
+	}
+	_, offset := c.Zone()
+	_, before := start.Add(-time.Second).Zone()
+	return start, offset - before
+}
+
+// skipped c 是时钟拨快的时刻，并且被跳过的墙上时间中有匹配的
+func (s *SpecSchedule) skipped(c time.Time) bool {
+	if s.Hour&allHours == allHours {
+		return false
+	}
+	start, change := offsetChange(c)
+	if change <= 0 || !c.Equal(start) {
+		return false
+	}
+	wall := time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), c.Minute(), c.Second(), 0, time.UTC)
+	for w := wall.Add(-time.Duration(change) * time.Second); w.Before(wall); w = w.Add(time.Second) {
+		if s.wallMatches(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated c 处在时钟拨回后重复的那段墙上时间中，返回重复结束的时刻
+func (s *SpecSchedule) repeated(c time.Time) (time.Time, bool) {
+	if s.Hour&allHours == allHours {
+		return time.Time{}, false
+	}
+	start, change := offsetChange(c)
+	if change >= 0 {
+		return time.Time{}, false
+	}
+	end := start.Add(time.Duration(-change) * time.Second)
+	return end, c.Before(end)
+}
@@ -0,0 +1,155 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustTime(t *testing.T, loc *time.Location, value string) time.Time {
+	t.Helper()
+	tm, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestParseNext(t *testing.T) {
+	cases := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2024-01-01 10:07:30", "2024-01-01 10:15:00"},
+		{"0 9 * * MON-FRI", "2024-01-06 12:00:00", "2024-01-08 09:00:00"},
+		{"30 */20 9-17 * * *", "2024-01-01 17:40:30", "2024-01-02 09:00:30"},
+		{"0 0 1,15 * *", "2024-01-15 00:00:00", "2024-02-01 00:00:00"},
+		{"0 0 * Feb 0", "2024-01-01 00:00:00", "2024-02-04 00:00:00"},
+		{"0 0 * * 7", "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"0 0 31 * *", "2024-04-01 00:00:00", "2024-05-31 00:00:00"},
+		// 日期和星期都限定时满足任一即可
+		{"0 12 13 * 5", "2024-09-01 00:00:00", "2024-09-06 12:00:00"},
+		{"0 12 13 * 5", "2024-09-07 00:00:00", "2024-09-13 12:00:00"},
+		{"5/20 * * * *", "2024-01-01 00:25:00", "2024-01-01 00:45:00"},
+		{"@hourly", "2024-01-01 10:00:00", "2024-01-01 11:00:00"},
+		{"@daily", "2024-01-01 10:00:00", "2024-01-02 00:00:00"},
+		{"@weekly", "2024-01-01 10:00:00", "2024-01-07 00:00:00"},
+		{"@monthly", "2024-01-31 10:00:00", "2024-02-01 00:00:00"},
+		{"@yearly", "2024-01-01 00:00:00", "2025-01-01 00:00:00"},
+		{"@every 90s", "2024-01-01 10:00:00", "2024-01-01 10:01:30"},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			s, err := ParseInLocation(c.spec, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := s.Next(mustTime(t, time.UTC, c.from))
+			if want := mustTime(t, time.UTC, c.want); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", c.from, got, want)
+			}
+		})
+	}
+
+	s, _ := ParseInLocation("0 0 30 2 *", time.UTC)
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Feb 30 should never match, got %s", next)
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	s, err := ParseInLocation("CRON_TZ=Asia/Shanghai 0 8 * * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Next(mustTime(t, time.UTC, "2023-12-31 12:00:00"))
+	if want := mustTime(t, time.UTC, "2024-01-01 00:00:00"); !got.Equal(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	s, _ = ParseInLocation("0 8 * * *", shanghai)
+	if got := s.Next(mustTime(t, time.UTC, "2023-12-31 12:00:00")); !got.Equal(mustTime(t, time.UTC, "2024-01-01 00:00:00")) {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-03-10 02:00 拨快到 03:00，2024-11-03 02:00 拨回到 01:00
+	cases := []struct {
+		name string
+		spec string
+		from time.Time
+		want []string
+	}{
+		{
+			name: "skipped fixed time runs at the transition",
+			spec: "30 2 * * *",
+			from: mustTime(t, ny, "2024-03-10 00:00:00"),
+			want: []string{"2024-03-10T03:00:00-04:00", "2024-03-11T02:30:00-04:00"},
+		},
+		{
+			name: "hourly jobs skip the missing hour",
+			spec: "30 * * * *",
+			from: mustTime(t, ny, "2024-03-10 01:00:00"),
+			want: []string{"2024-03-10T01:30:00-05:00", "2024-03-10T03:30:00-04:00"},
+		},
+		{
+			name: "repeated fixed time runs once",
+			spec: "30 1 * * *",
+			from: mustTime(t, ny, "2024-11-03 00:00:00"),
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "hourly jobs run in both repeated hours",
+			spec: "30 * * * *",
+			from: mustTime(t, ny, "2024-11-03 01:00:00"),
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-03T01:30:00-05:00", "2024-11-03T02:30:00-05:00"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ParseInLocation(c.spec, ny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := c.from
+			for _, want := range c.want {
+				next = s.Next(next)
+				if got := next.Format(time.RFC3339); got != want {
+					t.Fatalf("got %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * * BLAH",
+		"@weird",
+		"@every -1s",
+		"@every soon",
+		"CRON_TZ=Mars/Olympus * * * * *",
+	}
+	for _, spec := range specs {
+		if _, err := Parse(spec); !errors.Is(err, InvalidSpecError) {
+			t.Errorf("Parse(%q) = %v, want InvalidSpecError", spec, err)
+		}
+	}
+}
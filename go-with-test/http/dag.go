@@ -0,0 +1,296 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	UnknownRunnerError   = errors.New("unknown runner")
+	DependencyCycleError = errors.New("dependency cycle")
+	OutputTypeError      = errors.New("unexpected output type")
+)
+
+// NewInput 以 deps 作为依赖的输出构造 Input，用于在 Task 之外直接调用 Runner，例如由任务队列执行
+func NewInput(deps map[string]any) *Input {
+	return &Input{deps: deps}
+}
+
+// Dep 返回依赖的子任务 name 的输出，只有在 AddRunner 时声明过的依赖才能取到
+func (in *Input) Dep(name string) (any, bool) {
+	v, ok := in.deps[name]
+	return v, ok
+}
+
+// Output 以类型 T 取出依赖的子任务 name 的输出
+func Output[T any](in *Input, name string) (T, error) {
+	var zero T
+	v, ok := in.Dep(name)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is not a dependency", UnknownRunnerError, name)
+	}
+	out, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s produced %T, want %T", OutputTypeError, name, v, zero)
+	}
+	return out, nil
+}
+
+// Typed 把返回 (T, error) 的函数适配为 Runner，返回值写入 Result
+func Typed[T any](fn func(ctx context.Context, input *Input) (T, error)) Runner {
+	return RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		result.Output, result.Err = fn(ctx, input)
+	})
+}
+
+// Status 子任务的执行状态
+type Status int
+
+const (
+	// Skipped 因为其它子任务失败或者被取消而没有执行
+	Skipped Status = iota
+	Succeeded
+	Failed
+	// Canceled 执行过程中 ctx 被取消，返回的是 ctx 的错误
+	Canceled
+)
+
+func (s Status) String() string {
+	switch s {
+	case Skipped:
+		return "skipped"
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// RunnerReport 单个子任务的执行情况
+type RunnerReport struct {
+	Name      string
+	DependsOn []string
+	Status    Status
+	// Start 相对整个任务开始的时间
+	Start    time.Duration
+	Duration time.Duration
+	Err      error
+}
+
+// Report 一次 Execute 的报告，Runners 按开始执行的先后排列，跳过的排在最后
+type Report struct {
+	Task     string
+	Duration time.Duration
+	Runners  []RunnerReport
+}
+
+// String 以表格形式输出报告
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "task %s finished in %s\n", r.Task, r.Duration.Round(time.Millisecond))
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUNNER\tSTATUS\tSTART\tDURATION\tDEPENDS ON\tERROR")
+	for _, rr := range r.Runners {
+		errText := ""
+		if rr.Err != nil {
+			errText = rr.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rr.Name, rr.Status,
+			rr.Start.Round(time.Millisecond), rr.Duration.Round(time.Millisecond), strings.Join(rr.DependsOn, ","), errText)
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// order 校验依赖并返回一个拓扑序，同一层按名字排序保证结果稳定
+func (t *Task) order() ([]string, error) {
+	names := make([]string, 0, len(t.runners))
+	for name := range t.runners {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int, len(names))
+	var (
+		order []string
+		stack []string
+	)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			i := slices.Index(stack, name)
+			return fmt.Errorf("%w: %s", DependencyCycleError, strings.Join(append(stack[i:], name), " -> "))
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range t.deps[name] {
+			if _, ok := t.runners[dep]; !ok {
+				return fmt.Errorf("%w: %s depends on %s", UnknownRunnerError, name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+type runnerResult struct {
+	name   string
+	output any
+	err    error
+	start  time.Time
+	end    time.Time
+}
+
+// Execute 按依赖关系执行所有子任务：依赖全部成功后才会开始，同时最多执行 parallelism 个。
+// 任何一个子任务失败都会取消 ctx，不再启动新的子任务，等正在执行的返回后返回第一个错误。
+func (t *Task) Execute(ctx context.Context) (*Report, error) {
+	order, err := t.order()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	parallelism := t.parallelism
+	if parallelism <= 0 {
+		parallelism = len(order)
+	}
+
+	// pending 每个子任务还未完成的依赖数，dependents 反向的依赖关系
+	pending := make(map[string]int, len(order))
+	dependents := make(map[string][]string, len(order))
+	for _, name := range order {
+		pending[name] = len(t.deps[name])
+		for _, dep := range t.deps[name] {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	var ready []string
+	for _, name := range order {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	start := time.Now()
+	outputs := make(map[string]any, len(order))
+	reports := make(map[string]*RunnerReport, len(order))
+	var started []string
+	results := make(chan runnerResult)
+	running := 0
+	var firstErr error
+
+	launch := func(name string) {
+		input := &Input{deps: make(map[string]any, len(t.deps[name]))}
+		for _, dep := range t.deps[name] {
+			input.deps[dep] = outputs[dep]
+		}
+		runner := t.runners[name]
+		running++
+		started = append(started, name)
+		go func() {
+			r := runnerResult{name: name, start: time.Now()}
+			r.output, r.err = runSafely(ctx, runner, input)
+			r.end = time.Now()
+			results <- r
+		}()
+	}
+
+	for len(ready) > 0 || running > 0 {
+		for len(ready) > 0 && running < parallelism && firstErr == nil && ctx.Err() == nil {
+			launch(ready[0])
+			ready = ready[1:]
+		}
+		if running == 0 {
+			// 出错或者 ctx 被取消，剩下的子任务不再执行
+			break
+		}
+
+		r := <-results
+		running--
+		rr := &RunnerReport{
+			Name:      r.name,
+			DependsOn: t.deps[r.name],
+			Start:     r.start.Sub(start),
+			Duration:  r.end.Sub(r.start),
+			Err:       r.err,
+			Status:    Succeeded,
+		}
+		reports[r.name] = rr
+
+		switch {
+		case r.err == nil:
+			outputs[r.name] = r.output
+			for _, d := range dependents[r.name] {
+				if pending[d]--; pending[d] == 0 {
+					ready = append(ready, d)
+				}
+			}
+		case ctx.Err() != nil:
+			rr.Status = Canceled
+		default:
+			rr.Status = Failed
+			firstErr = fmt.Errorf("runner %s: %w", r.name, r.err)
+			cancel(firstErr)
+		}
+	}
+
+	report := &Report{Task: t.name, Duration: time.Since(start)}
+	slices.SortStableFunc(started, func(a, b string) int {
+		return cmp.Compare(reports[a].Start, reports[b].Start)
+	})
+	for _, name := range started {
+		report.Runners = append(report.Runners, *reports[name])
+	}
+	for _, name := range order {
+		if _, ok := reports[name]; !ok {
+			report.Runners = append(report.Runners, RunnerReport{Name: name, DependsOn: t.deps[name], Status: Skipped})
+		}
+	}
+
+	if firstErr != nil {
+		return report, firstErr
+	}
+	return report, ctx.Err()
+}
+
+// runSafely 执行子任务，panic 转换为错误，避免一个子任务拖垮整个任务
+func runSafely(ctx context.Context, runner Runner, input *Input) (output any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+			log.Printf("runner panicked: %v", p)
+		}
+	}()
+	result := &Result{}
+	runner.Run(ctx, input, result)
+	return result.Output, result.Err
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestTaskExecute(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	task := NewTask("report", 0)
+	task.AddRunner("load-users", Typed(func(ctx context.Context, input *Input) ([]user, error) {
+		record("load-users")
+		return []user{{1, "Chris"}, {2, "Cleo"}}, nil
+	}))
+	task.AddRunner("load-scores", Typed(func(ctx context.Context, input *Input) (map[int]int, error) {
+		record("load-scores")
+		return map[int]int{1: 20, 2: 33}, nil
+	}))
+	task.AddRunner("render", Typed(func(ctx context.Context, input *Input) (string, error) {
+		record("render")
+		users, err := Output[[]user](input, "load-users")
+		if err != nil {
+			return "", err
+		}
+		scores, err := Output[map[int]int](input, "load-scores")
+		if err != nil {
+			return "", err
+		}
+		var lines []string
+		for _, u := range users {
+			lines = append(lines, u.Name+":"+strings.Repeat("*", scores[u.ID]/10))
+		}
+		return strings.Join(lines, " "), nil
+	}), "load-users", "load-scores")
+	var rendered string
+	task.AddRunner("publish", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		record("publish")
+		rendered, result.Err = Output[string](input, "render")
+	}), "render")
+
+	report, err := task.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered != "Chris:** Cleo:***" {
+		t.Errorf("got %q", rendered)
+	}
+	if got := strings.Join(order[2:], ","); got != "render,publish" {
+		t.Errorf("dependents ran as %v", order)
+	}
+	if len(report.Runners) != 4 {
+		t.Fatalf("got %d runner reports", len(report.Runners))
+	}
+	for _, rr := range report.Runners {
+		if rr.Status != Succeeded {
+			t.Errorf("%s: %s", rr.Name, rr.Status)
+		}
+	}
+	if last := report.Runners[3]; last.Name != "publish" || last.DependsOn[0] != "render" {
+		t.Errorf("got %+v", last)
+	}
+	if s := report.String(); !strings.Contains(s, "RUNNER") || !strings.Contains(s, "load-users,load-scores") {
+		t.Errorf("unexpected report:\n%s", s)
+	}
+}
+
+func TestTaskParallelism(t *testing.T) {
+	var running, peak int32
+	task := NewTask("bounded", 2)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		task.AddRunner(name, RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}))
+	}
+	if _, err := task.Execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if peak != 2 {
+		t.Errorf("peak concurrency %d, want 2", peak)
+	}
+}
+
+func TestTaskFailure(t *testing.T) {
+	boom := errors.New("boom")
+	task := NewTask("failing", 0)
+	task.AddRunner("fail", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		time.Sleep(10 * time.Millisecond)
+		result.Err = boom
+	}))
+	task.AddRunner("slow", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		select {
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}))
+	task.AddRunner("after-fail", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		t.Error("dependents of a failed runner must not run")
+	}), "fail")
+
+	begin := time.Now()
+	report, err := task.Execute(context.Background())
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "runner fail") {
+		t.Fatalf("got %v", err)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Error("running siblings should be cancelled")
+	}
+
+	statuses := map[string]Status{}
+	for _, rr := range report.Runners {
+		statuses[rr.Name] = rr.Status
+	}
+	want := map[string]Status{"fail": Failed, "slow": Canceled, "after-fail": Skipped}
+	for name, s := range want {
+		if statuses[name] != s {
+			t.Errorf("%s: got %s, want %s", name, statuses[name], s)
+		}
+	}
+
+	t.Run("panic", func(t *testing.T) {
+		task := NewTask("panicking", 0)
+		task.AddRunner("panic", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+			panic("oops")
+		}))
+		if _, err := task.Execute(context.Background()); err == nil || !strings.Contains(err.Error(), "oops") {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("caller cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		task := NewTask("cancelled", 0)
+		task.AddRunner("first", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+			cancel()
+		}))
+		task.AddRunner("second", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+			t.Error("must not start after cancellation")
+		}), "first")
+		report, err := task.Execute(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v", err)
+		}
+		if report.Runners[1].Status != Skipped {
+			t.Errorf("got %+v", report.Runners[1])
+		}
+	})
+}
+
+func TestTaskValidation(t *testing.T) {
+	noop := RunnerFunc(f1)
+
+	task := NewTask("cycle", 0)
+	task.AddRunner("a", noop, "c")
+	task.AddRunner("b", noop, "a")
+	task.AddRunner("c", noop, "b")
+	_, err := task.Execute(context.Background())
+	if !errors.Is(err, DependencyCycleError) || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Errorf("got %v", err)
+	}
+
+	task = NewTask("unknown", 0)
+	task.AddRunner("a", noop, "missing")
+	if _, err := task.Execute(context.Background()); !errors.Is(err, UnknownRunnerError) {
+		t.Errorf("got %v", err)
+	}
+
+	task = NewTask("types", 0)
+	task.AddRunner("number", Typed(func(ctx context.Context, input *Input) (int, error) { return 1, nil }))
+	task.AddRunner("reader", RunnerFunc(func(ctx context.Context, input *Input, result *Result) {
+		_, result.Err = Output[string](input, "number")
+	}), "number")
+	if _, err := task.Execute(context.Background()); !errors.Is(err, OutputTypeError) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -10,11 +10,16 @@ import (
 	"sync"
 )
 
-// Input Run参数
-type Input struct{}
+// Input Run参数，通过 Dep 或 Output 取得所依赖的子任务的输出
+type Input struct {
+	deps map[string]any
+}
 
-// Result Run结果
-type Result struct{}
+// Result Run结果，Output 会传给依赖它的子任务，Err 不为 nil 时整个任务失败
+type Result struct {
+	Output any
+	Err    error
+}
 
 // Runner 只有一个Run方法的接口
 type Runner interface {
@@ -41,14 +46,30 @@ type Task struct {
 	// TODO: 这里可以进一步优化，比如：
 	//runners map[string]RunnerWithInputResult
 	runners map[string]Runner
+	// deps 子任务依赖的其它子任务
+	deps map[string][]string
+	// parallelism 同时执行的子任务数，0 表示不限制
+	parallelism int
+}
+
+// NewTask 创建任务，parallelism 为同时执行的子任务数，0 表示不限制
+func NewTask(name string, parallelism int) *Task {
+	return &Task{name: name, runners: make(map[string]Runner), parallelism: parallelism}
 }
 
-// AddRunner 添加子任务
-func (t *Task) AddRunner(name string, runner Runner) {
+// AddRunner 添加子任务，dependsOn 中的子任务都成功后才会执行，其输出通过 Input 传入
+func (t *Task) AddRunner(name string, runner Runner, dependsOn ...string) {
+	if t.runners == nil {
+		t.runners = make(map[string]Runner)
+	}
+	if t.deps == nil {
+		t.deps = make(map[string][]string)
+	}
 	t.runners[name] = runner
+	t.deps[name] = dependsOn
 }
 
-// Run 批量执行任务
+// Run 批量执行任务，忽略依赖关系同时执行所有子任务，也不会返回错误。需要依赖、并发控制与报告时使用 Execute。
 func (t *Task) Run() {
 	// TODO: 这里可以简化Run的定义，只传入context，参数在 AddRunner 时就加入到 runners 中
 	var wg sync.WaitGroup
@@ -71,7 +92,7 @@ func (t *Task) Run() {
 		go func(name string, runner Runner) {
 			defer wg.Done()
 			log.Printf("[%s] started\n", name)
-			runner.Run(context.Background(), &Input{}, &Result{})
+			runner.Run(context.Background(), &Input{deps: map[string]any{}}, &Result{})
 		}(name, runner)
 	}
 
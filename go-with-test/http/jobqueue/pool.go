@@ -0,0 +1,179 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gaohongsong/go-playground/go-with-test/http"
+)
+
+// Handler 处理一个任务，与 http.Runner 一样以 ctx 控制超时和取消。
+// 返回 nil 时任务被确认删除，返回错误时按退避时间重试。
+type Handler interface {
+	Handle(ctx context.Context, job Job) error
+}
+
+// HandlerFunc 把函数适配为 Handler
+type HandlerFunc func(ctx context.Context, job Job) error
+
+func (f HandlerFunc) Handle(ctx context.Context, job Job) error {
+	return f(ctx, job)
+}
+
+// JobDep FromRunner 调用 Runner 时任务在 Input 中的名字：
+//
+//	job, err := http.Output[jobqueue.Job](input, jobqueue.JobDep)
+const JobDep = "job"
+
+// FromRunner 把 http.Runner 适配为 Handler，任务通过 Input 的 JobDep 传入，Result.Err 作为处理结果
+func FromRunner(r http.Runner) Handler {
+	return HandlerFunc(func(ctx context.Context, job Job) error {
+		var result http.Result
+		r.Run(ctx, http.NewInput(map[string]any{JobDep: job}), &result)
+		return result.Err
+	})
+}
+
+// Pool 从 Queue 中取任务交给 Handler 执行的 worker 池
+type Pool struct {
+	Queue   *Queue
+	Handler Handler
+	// Workers 并发执行的任务数，默认 1
+	Workers int
+	// PollInterval 队列为空时多久检查一次延迟和租约过期的任务，默认 1s
+	PollInterval time.Duration
+	// HandlerTimeout 单个任务最长的处理时间，默认不限制。
+	// 处理期间每隔 VisibilityTimeout/3 自动续租，租约总是比 Handler 的 ctx 晚到期
+	HandlerTimeout time.Duration
+	// OnError Ack/Nack 等队列操作失败时调用，默认写日志
+	OnError func(job Job, err error)
+}
+
+// Run 启动 worker 直到 ctx 被取消，等待正在执行的任务返回后才返回。
+// ctx 被取消导致失败的任务会被放回队列，不计入尝试次数。
+func (p *Pool) Run(ctx context.Context) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for ctx.Err() == nil {
+		job, err := p.Queue.Dequeue()
+		if err == nil {
+			p.process(ctx, job)
+			continue
+		}
+		if !errors.Is(err, NoJobError) {
+			p.report(job, err)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(interval)
+		select {
+		case <-ctx.Done():
+		case <-p.Queue.Ready():
+		case <-timer.C:
+		}
+	}
+}
+
+// process 执行一个任务，执行期间在后台续租；续租失败时取消 Handler 的 ctx，
+// 避免任务被重新投递给其它 worker 后仍在执行
+func (p *Pool) process(ctx context.Context, job Job) {
+	var (
+		hctx   context.Context
+		cancel context.CancelFunc
+	)
+	if p.HandlerTimeout > 0 {
+		hctx, cancel = context.WithTimeout(ctx, p.HandlerTimeout)
+	} else {
+		hctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		p.renew(job, stop, cancel)
+	}()
+	err := handleSafely(hctx, p.Handler, job)
+	close(stop)
+	<-renewed
+
+	switch {
+	case err == nil:
+		err = p.Queue.Ack(job)
+	case ctx.Err() != nil:
+		err = p.Queue.Release(job)
+	default:
+		err = p.Queue.Nack(job, err)
+	}
+	if err != nil {
+		p.report(job, err)
+	}
+}
+
+// renew 每隔三分之一的可见性超时续租一次，直到 stop 被关闭，续租失败时调用 cancel
+func (p *Pool) renew(job Job, stop <-chan struct{}, cancel context.CancelFunc) {
+	timeout := p.Queue.opts.VisibilityTimeout
+	ticker := time.NewTicker(timeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := p.Queue.Extend(job, timeout); err != nil {
+				p.report(job, fmt.Errorf("extend lease: %w", err))
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (p *Pool) report(job Job, err error) {
+	if p.OnError != nil {
+		p.OnError(job, err)
+		return
+	}
+	log.Printf("job %s: %v", job.ID, err)
+}
+
+// handleSafely panic 转换为错误，任务按失败重试
+func handleSafely(ctx context.Context, h Handler, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.Handle(ctx, job)
+}
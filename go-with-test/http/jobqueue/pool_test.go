@@ -0,0 +1,230 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaohongsong/go-playground/go-with-test/http"
+)
+
+func TestPool(t *testing.T) {
+	q, err := Open(&MemoryStore{}, Options{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []string{"ok", "flaky", "broken", "panic"} {
+		q.Enqueue([]byte(payload))
+	}
+
+	var mu sync.Mutex
+	handled := map[string]int{}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &Pool{
+		Queue:        q,
+		Workers:      3,
+		PollInterval: 10 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job Job) error {
+			mu.Lock()
+			handled[string(job.Payload)]++
+			mu.Unlock()
+			switch string(job.Payload) {
+			case "flaky":
+				if job.Attempts == 1 {
+					return errors.New("try again")
+				}
+			case "broken":
+				return errors.New("broken")
+			case "panic":
+				panic("oops")
+			}
+			return nil
+		}),
+	}
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for q.Stats() != (Stats{Dead: 2}) {
+		select {
+		case <-deadline:
+			t.Fatalf("got %+v", q.Stats())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	want := map[string]int{"ok": 1, "flaky": 2, "broken": 2, "panic": 2}
+	for payload, n := range want {
+		if handled[payload] != n {
+			t.Errorf("%s handled %d times, want %d", payload, handled[payload], n)
+		}
+	}
+	dead := q.DeadLetters()
+	if len(dead) != 2 || dead[0].LastError != "broken" || dead[1].LastError != "panic: oops" {
+		t.Errorf("got %+v", dead)
+	}
+}
+
+func TestPoolShutdown(t *testing.T) {
+	q, err := Open(&MemoryStore{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue([]byte("long"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	pool := &Pool{Queue: q, Handler: HandlerFunc(func(ctx context.Context, job Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})}
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+	<-done
+
+	// 被中断的任务放回队列，不计入尝试次数
+	job, err := q.Dequeue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Attempts != 1 || job.LastError != "" {
+		t.Errorf("got %+v", job)
+	}
+}
+
+// 处理时间超过可见性超时的任务在后台续租，完成后可以正常确认
+func TestPoolRenewsLease(t *testing.T) {
+	q, err := Open(&MemoryStore{}, Options{VisibilityTimeout: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue([]byte("slow"))
+
+	var (
+		mu      sync.Mutex
+		handled int
+		errs    []error
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := &Pool{
+		Queue:        q,
+		Workers:      2,
+		PollInterval: 5 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job Job) error {
+			mu.Lock()
+			handled++
+			mu.Unlock()
+			select {
+			case <-time.After(150 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}),
+		OnError: func(job Job, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for q.Stats() != (Stats{}) {
+		select {
+		case <-deadline:
+			t.Fatalf("got %+v", q.Stats())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	if handled != 1 || len(errs) != 0 {
+		t.Errorf("handled %d times with errors %v", handled, errs)
+	}
+}
+
+func TestPoolHandlerTimeout(t *testing.T) {
+	q, err := Open(&MemoryStore{}, Options{MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue([]byte("stuck"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := &Pool{
+		Queue:          q,
+		PollInterval:   5 * time.Millisecond,
+		HandlerTimeout: 20 * time.Millisecond,
+		Handler: HandlerFunc(func(ctx context.Context, job Job) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}
+	go pool.Run(ctx)
+
+	deadline := time.After(5 * time.Second)
+	for q.Stats() != (Stats{Dead: 1}) {
+		select {
+		case <-deadline:
+			t.Fatalf("got %+v", q.Stats())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if dead := q.DeadLetters(); dead[0].LastError != context.DeadlineExceeded.Error() {
+		t.Errorf("got %+v", dead[0])
+	}
+}
+
+func TestFromRunner(t *testing.T) {
+	q, err := Open(&MemoryStore{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue([]byte("hello"))
+	job, err := q.Dequeue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	h := FromRunner(http.RunnerFunc(func(ctx context.Context, input *http.Input, result *http.Result) {
+		job, err := http.Output[Job](input, JobDep)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		got = string(job.Payload)
+		result.Err = errors.New("failed")
+	}))
+	if err := h.Handle(context.Background(), job); err == nil || err.Error() != "failed" {
+		t.Errorf("got %v, want the Result.Err", err)
+	}
+	if got != "hello" {
+		t.Errorf("runner got payload %q", got)
+	}
+}
@@ -0,0 +1,421 @@
+// Package jobqueue 持久化的任务队列：任务在被确认 (Ack) 之前一直保存在 Store 中，
+// 取出的任务在可见性超时内没有确认会被重新投递（至少一次），失败的任务按退避时间重试，
+// 超过最大次数后进入死信队列。Pool 用一组 worker 执行 Handler。
+package jobqueue
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gaohongsong/go-playground/go-with-test/http/cron"
+)
+
+var (
+	NoJobError       = errors.New("no job ready")
+	JobNotFoundError = errors.New("job not found")
+	// LeaseExpiredError 任务的租约已经过期，可能已经被投递给了其它 worker
+	LeaseExpiredError = errors.New("job lease expired")
+)
+
+// State 任务状态
+type State string
+
+const (
+	// Ready 等待被取出，RunAt 之前不可见
+	Ready State = "ready"
+	// Leased 已被 worker 取出，LeasedUntil 之前不会再被投递
+	Leased State = "leased"
+	// Dead 重试次数用完，留在死信队列中等待人工处理
+	Dead State = "dead"
+)
+
+// Job 队列中的任务
+type Job struct {
+	ID      string `json:"id"`
+	Payload []byte `json:"payload"`
+	State   State  `json:"state"`
+	// Attempts 已经投递的次数，MaxAttempts 为 0 时使用队列的默认值
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts,omitempty"`
+	RunAt       time.Time `json:"runAt"`
+	LeasedUntil time.Time `json:"leasedUntil,omitempty"`
+	// Lease 每次投递生成新的租约，Ack/Nack 时用来确认调用方持有的是最新一次投递
+	Lease     string    `json:"lease,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Seq 入队的顺序，同时可见的任务先进先出
+	Seq uint64 `json:"seq"`
+}
+
+// Options 队列配置
+type Options struct {
+	// VisibilityTimeout 任务被取出后多久没有确认就重新投递，默认 30s
+	VisibilityTimeout time.Duration
+	// MaxAttempts 默认的最大投递次数，默认 5
+	MaxAttempts int
+	// Backoff 第 attempt 次失败后等待多久再重试，默认从 1s 开始翻倍，最多 5m
+	Backoff func(attempt int) time.Duration
+	// Clock 默认使用真实时间，测试中可以替换为 cron.FakeClock
+	Clock cron.Clock
+}
+
+// Stats 队列中各状态的任务数
+type Stats struct {
+	Ready, Delayed, Leased, Dead int
+}
+
+// Queue 持久化任务队列，可以被多个 goroutine 同时使用
+type Queue struct {
+	store Store
+	opts  Options
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	seq  uint64
+	// notify 有新的任务可见时通知等待中的 worker
+	notify chan struct{}
+}
+
+// Open 从 store 中恢复队列。上次进程退出时处于 Leased 状态的任务会在租约过期后重新投递。
+func Open(store Store, opts Options) (*Queue, error) {
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 30 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialBackoff(time.Second, 5*time.Minute)
+	}
+	if opts.Clock == nil {
+		opts.Clock = cron.SystemClock
+	}
+
+	jobs, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		store:  store,
+		opts:   opts,
+		jobs:   make(map[string]*Job, len(jobs)),
+		notify: make(chan struct{}, 1),
+	}
+	for i := range jobs {
+		job := jobs[i]
+		q.jobs[job.ID] = &job
+		q.seq = max(q.seq, job.Seq)
+	}
+	return q, nil
+}
+
+// ExponentialBackoff 第 n 次失败后等待 base*2^(n-1)，最多 limit
+func ExponentialBackoff(base, limit time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < limit; i++ {
+			d *= 2
+		}
+		return min(d, limit)
+	}
+}
+
+// EnqueueOption Enqueue 的可选项
+type EnqueueOption func(*Job)
+
+// WithDelay 延迟 d 之后才可以被取出
+func WithDelay(d time.Duration) EnqueueOption {
+	return func(j *Job) {
+		j.RunAt = j.RunAt.Add(d)
+	}
+}
+
+// WithMaxAttempts 覆盖队列默认的最大投递次数
+func WithMaxAttempts(n int) EnqueueOption {
+	return func(j *Job) {
+		j.MaxAttempts = n
+	}
+}
+
+// Enqueue 添加任务，保存成功后才返回任务 ID
+func (q *Queue) Enqueue(payload []byte, opts ...EnqueueOption) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.opts.Clock.Now()
+	q.seq++
+	job := &Job{ID: id, Payload: payload, State: Ready, RunAt: now, CreatedAt: now, Seq: q.seq}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	q.jobs[id] = job
+	if err := q.save(); err != nil {
+		delete(q.jobs, id)
+		return "", err
+	}
+	q.wake()
+	return id, nil
+}
+
+// Dequeue 取出最早可见的任务并加上租约，没有可见的任务时返回 NoJobError。
+// 租约过期而没有确认的任务同样可见，这就是至少一次投递：处理任务的代码需要是幂等的。
+func (q *Queue) Dequeue() (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.opts.Clock.Now()
+
+	var next *Job
+	// dead 记录本次改为 Dead 的任务改动前的样子，保存失败时全部恢复
+	var dead []*Job
+	var before []Job
+	restore := func() {
+		for i, job := range dead {
+			*job = before[i]
+		}
+	}
+	for _, job := range q.jobs {
+		if !q.visible(job, now) {
+			continue
+		}
+		// 租约过期时这次投递也算一次尝试，次数用完的直接进入死信队列
+		if job.State == Leased && job.Attempts >= q.maxAttempts(job) {
+			dead, before = append(dead, job), append(before, *job)
+			job.State = Dead
+			job.LastError = fmt.Sprintf("lease expired after %d attempts", job.Attempts)
+			continue
+		}
+		if next == nil || job.RunAt.Before(next.RunAt) || (job.RunAt.Equal(next.RunAt) && job.Seq < next.Seq) {
+			next = job
+		}
+	}
+	if next == nil {
+		if len(dead) > 0 {
+			if err := q.save(); err != nil {
+				restore()
+				return Job{}, err
+			}
+		}
+		return Job{}, NoJobError
+	}
+
+	lease, err := newID()
+	if err != nil {
+		restore()
+		return Job{}, err
+	}
+	prev := *next
+	next.State = Leased
+	next.Attempts++
+	next.Lease = lease
+	next.LeasedUntil = now.Add(q.opts.VisibilityTimeout)
+	if err := q.save(); err != nil {
+		*next = prev
+		restore()
+		return Job{}, err
+	}
+	return *next, nil
+}
+
+// Ack 确认任务已经处理完成，从队列中删除
+func (q *Queue) Ack(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	current, err := q.leased(job)
+	if err != nil {
+		return err
+	}
+	delete(q.jobs, job.ID)
+	if err := q.save(); err != nil {
+		q.jobs[job.ID] = current
+		return err
+	}
+	return nil
+}
+
+// Nack 任务处理失败，按退避时间重试，次数用完后进入死信队列
+func (q *Queue) Nack(job Job, cause error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	current, err := q.leased(job)
+	if err != nil {
+		return err
+	}
+
+	prev := *current
+	current.Lease, current.LeasedUntil = "", time.Time{}
+	if cause != nil {
+		current.LastError = cause.Error()
+	}
+	if current.Attempts >= q.maxAttempts(current) {
+		current.State = Dead
+	} else {
+		current.State = Ready
+		current.RunAt = q.opts.Clock.Now().Add(q.opts.Backoff(current.Attempts))
+	}
+	if err := q.save(); err != nil {
+		*current = prev
+		return err
+	}
+	return nil
+}
+
+// Release 放回任务且不计入尝试次数，用于 worker 正常退出时交还未开始处理的任务
+func (q *Queue) Release(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	current, err := q.leased(job)
+	if err != nil {
+		return err
+	}
+	prev := *current
+	current.State = Ready
+	current.Attempts--
+	current.Lease, current.LeasedUntil = "", time.Time{}
+	current.RunAt = q.opts.Clock.Now()
+	if err := q.save(); err != nil {
+		*current = prev
+		return err
+	}
+	q.wake()
+	return nil
+}
+
+// Extend 延长租约，处理时间可能超过可见性超时的任务应当定期调用，Pool 会在处理期间自动调用
+func (q *Queue) Extend(job Job, d time.Duration) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	current, err := q.leased(job)
+	if err != nil {
+		return Job{}, err
+	}
+	prev := *current
+	current.LeasedUntil = q.opts.Clock.Now().Add(d)
+	if err := q.save(); err != nil {
+		*current = prev
+		return Job{}, err
+	}
+	return *current, nil
+}
+
+// DeadLetters 返回死信队列中的任务，按入队顺序排列
+func (q *Queue) DeadLetters() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var dead []Job
+	for _, job := range q.jobs {
+		if job.State == Dead {
+			dead = append(dead, *job)
+		}
+	}
+	slices.SortFunc(dead, func(a, b Job) int { return cmp.Compare(a.Seq, b.Seq) })
+	return dead
+}
+
+// Requeue 把死信队列中的任务重新放回队列，尝试次数清零
+func (q *Queue) Requeue(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok || job.State != Dead {
+		return fmt.Errorf("%w: %s is not a dead letter", JobNotFoundError, id)
+	}
+	prev := *job
+	job.State, job.Attempts, job.RunAt = Ready, 0, q.opts.Clock.Now()
+	if err := q.save(); err != nil {
+		*job = prev
+		return err
+	}
+	q.wake()
+	return nil
+}
+
+// Stats 返回各状态的任务数
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.opts.Clock.Now()
+	var s Stats
+	for _, job := range q.jobs {
+		switch {
+		case job.State == Dead:
+			s.Dead++
+		case job.State == Leased && now.Before(job.LeasedUntil):
+			s.Leased++
+		case job.RunAt.After(now):
+			s.Delayed++
+		default:
+			s.Ready++
+		}
+	}
+	return s
+}
+
+// Ready 有任务可能变为可见时会收到信号，等待中的 worker 可以借此提前醒来
+func (q *Queue) Ready() <-chan struct{} {
+	return q.notify
+}
+
+func (q *Queue) visible(job *Job, now time.Time) bool {
+	switch job.State {
+	case Ready:
+		return !job.RunAt.After(now)
+	case Leased:
+		return !job.LeasedUntil.After(now)
+	}
+	return false
+}
+
+func (q *Queue) maxAttempts(job *Job) int {
+	if job.MaxAttempts > 0 {
+		return job.MaxAttempts
+	}
+	return q.opts.MaxAttempts
+}
+
+// leased 返回调用方持有的租约仍然有效的任务，调用方需持有 q.mu
+func (q *Queue) leased(job Job) (*Job, error) {
+	current, ok := q.jobs[job.ID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", JobNotFoundError, job.ID)
+	}
+	if current.State != Leased || current.Lease != job.Lease || !q.opts.Clock.Now().Before(current.LeasedUntil) {
+		return nil, fmt.Errorf("%w: %s", LeaseExpiredError, job.ID)
+	}
+	return current, nil
+}
+
+// save 保存所有任务的快照，调用方需持有 q.mu
+func (q *Queue) save() error {
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return cmp.Compare(a.Seq, b.Seq) })
+	return q.store.Save(jobs)
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
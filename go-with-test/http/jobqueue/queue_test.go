@@ -0,0 +1,238 @@
+package jobqueue
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaohongsong/go-playground/go-with-test/http/cron"
+)
+
+func newTestQueue(t *testing.T, store Store) (*Queue, *cron.FakeClock) {
+	t.Helper()
+	clock := cron.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q, err := Open(store, Options{
+		VisibilityTimeout: 10 * time.Second,
+		MaxAttempts:       3,
+		Backoff:           ExponentialBackoff(time.Second, time.Minute),
+		Clock:             clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q, clock
+}
+
+func mustDequeue(t *testing.T, q *Queue) Job {
+	t.Helper()
+	job, err := q.Dequeue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestQueueOrder(t *testing.T) {
+	q, clock := newTestQueue(t, &MemoryStore{})
+	delayed, _ := q.Enqueue([]byte("delayed"), WithDelay(5*time.Second))
+	first, _ := q.Enqueue([]byte("first"))
+	second, _ := q.Enqueue([]byte("second"))
+
+	if job := mustDequeue(t, q); job.ID != first || string(job.Payload) != "first" || job.Attempts != 1 {
+		t.Errorf("got %+v", job)
+	}
+	if job := mustDequeue(t, q); job.ID != second {
+		t.Errorf("got %s, want second", job.Payload)
+	}
+	if _, err := q.Dequeue(); !errors.Is(err, NoJobError) {
+		t.Errorf("delayed job should not be visible yet: %v", err)
+	}
+	if s := q.Stats(); s != (Stats{Delayed: 1, Leased: 2}) {
+		t.Errorf("got %+v", s)
+	}
+
+	clock.Advance(5 * time.Second)
+	if job := mustDequeue(t, q); job.ID != delayed {
+		t.Errorf("got %s, want delayed", job.Payload)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	q, clock := newTestQueue(t, &MemoryStore{})
+	id, _ := q.Enqueue([]byte("flaky"))
+
+	job := mustDequeue(t, q)
+	if err := q.Nack(job, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Dequeue(); !errors.Is(err, NoJobError) {
+		t.Errorf("job should wait for the backoff: %v", err)
+	}
+	clock.Advance(time.Second)
+	job = mustDequeue(t, q)
+	if job.Attempts != 2 || job.LastError != "timeout" {
+		t.Errorf("got %+v", job)
+	}
+
+	if err := q.Nack(job, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if _, err := q.Dequeue(); !errors.Is(err, NoJobError) {
+		t.Errorf("second backoff should be 2s: %v", err)
+	}
+	clock.Advance(time.Second)
+	job = mustDequeue(t, q)
+
+	if err := q.Nack(job, errors.New("gave up")); err != nil {
+		t.Fatal(err)
+	}
+	dead := q.DeadLetters()
+	if len(dead) != 1 || dead[0].ID != id || dead[0].LastError != "gave up" {
+		t.Fatalf("got %+v", dead)
+	}
+
+	if err := q.Requeue(id); err != nil {
+		t.Fatal(err)
+	}
+	if job := mustDequeue(t, q); job.Attempts != 1 {
+		t.Errorf("requeue should reset attempts: %+v", job)
+	}
+	if err := q.Requeue(id); !errors.Is(err, JobNotFoundError) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	q, clock := newTestQueue(t, &MemoryStore{})
+	q.Enqueue([]byte("slow"), WithMaxAttempts(2))
+
+	stale := mustDequeue(t, q)
+	clock.Advance(10 * time.Second)
+
+	// 租约过期后重新投递，旧的租约不能再确认
+	job := mustDequeue(t, q)
+	if job.ID != stale.ID || job.Attempts != 2 {
+		t.Errorf("got %+v", job)
+	}
+	if err := q.Ack(stale); !errors.Is(err, LeaseExpiredError) {
+		t.Errorf("got %v", err)
+	}
+
+	job, err := q.Extend(job, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Second)
+	if _, err := q.Dequeue(); !errors.Is(err, NoJobError) {
+		t.Errorf("extended job should stay leased: %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, err := q.Dequeue(); !errors.Is(err, NoJobError) {
+		t.Errorf("got %v", err)
+	}
+	if dead := q.DeadLetters(); len(dead) != 1 || dead[0].State != Dead {
+		t.Errorf("job whose last lease expired should be dead: %+v", dead)
+	}
+}
+
+// flakyStore 在 fail 为 true 时保存失败
+type flakyStore struct {
+	MemoryStore
+	fail bool
+}
+
+func (s *flakyStore) Save(jobs []Job) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStore.Save(jobs)
+}
+
+func TestQueueDequeueSaveFailure(t *testing.T) {
+	store := &flakyStore{}
+	q, clock := newTestQueue(t, store)
+	q.Enqueue([]byte("a"), WithMaxAttempts(1))
+	q.Enqueue([]byte("b"), WithMaxAttempts(1))
+	mustDequeue(t, q)
+	mustDequeue(t, q)
+	clock.Advance(10 * time.Second)
+	q.Enqueue([]byte("c"))
+
+	// 两个租约过期的任务进入死信队列，c 被取出，保存失败时全部撤销
+	store.fail = true
+	if _, err := q.Dequeue(); err == nil {
+		t.Fatal("want the save error")
+	}
+	// 租约过期的任务重新可见，计入 Ready
+	if s := q.Stats(); s != (Stats{Ready: 3}) {
+		t.Errorf("got %+v", s)
+	}
+
+	store.fail = false
+	if job := mustDequeue(t, q); string(job.Payload) != "c" {
+		t.Errorf("got %s, want c", job.Payload)
+	}
+	if s := q.Stats(); s != (Stats{Leased: 1, Dead: 2}) {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestQueueRelease(t *testing.T) {
+	q, _ := newTestQueue(t, &MemoryStore{})
+	q.Enqueue([]byte("job"))
+
+	job := mustDequeue(t, q)
+	if err := q.Release(job); err != nil {
+		t.Fatal(err)
+	}
+	if job := mustDequeue(t, q); job.Attempts != 1 {
+		t.Errorf("release should not count as an attempt: %+v", job)
+	}
+	if err := q.Ack(job); !errors.Is(err, LeaseExpiredError) {
+		t.Errorf("released lease should be invalid: %v", err)
+	}
+	if err := q.Ack(Job{ID: "missing"}); !errors.Is(err, JobNotFoundError) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	q, clock := newTestQueue(t, NewFileStore(path))
+
+	acked, _ := q.Enqueue([]byte("acked"))
+	q.Enqueue([]byte("in-flight"))
+	q.Enqueue([]byte("pending"))
+	job := mustDequeue(t, q)
+	if job.ID != acked {
+		t.Fatalf("got %s", job.Payload)
+	}
+	if err := q.Ack(job); err != nil {
+		t.Fatal(err)
+	}
+	mustDequeue(t, q)
+
+	// 模拟进程重启：未确认的任务在租约过期后重新投递
+	restarted, err := Open(NewFileStore(path), Options{VisibilityTimeout: 10 * time.Second, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := restarted.Stats(); s != (Stats{Ready: 1, Leased: 1}) {
+		t.Errorf("got %+v", s)
+	}
+	if job := mustDequeue(t, restarted); string(job.Payload) != "pending" {
+		t.Errorf("got %s", job.Payload)
+	}
+	clock.Advance(10 * time.Second)
+	if job := mustDequeue(t, restarted); string(job.Payload) != "in-flight" || job.Attempts != 2 {
+		t.Errorf("got %+v", job)
+	}
+
+	id, _ := restarted.Enqueue([]byte("after restart"))
+	if seq := restarted.jobs[id].Seq; seq != 4 {
+		t.Errorf("sequence should continue after restart, got %d", seq)
+	}
+}
@@ -0,0 +1,87 @@
+package jobqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store 持久化队列中所有的任务。队列的每次变更都会整体保存一次快照，简单可靠，适合任务量不大的场景。
+type Store interface {
+	Load() ([]Job, error)
+	Save(jobs []Job) error
+}
+
+// MemoryStore 不持久化，用于测试
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs []Job
+}
+
+func (s *MemoryStore) Load() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.jobs...), nil
+}
+
+func (s *MemoryStore) Save(jobs []Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs[:0], jobs...)
+	return nil
+}
+
+// FileStore 以 JSON 保存在文件中。与 players.FileSystemStore 直接覆盖写入同一个文件不同，
+// 这里先写临时文件并 fsync 再 rename，进程在写入中途崩溃时不会留下半个文件。
+type FileStore struct {
+	path string
+}
+
+// NewFileStore 使用 path 保存任务，文件不存在时视为空队列
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Load() ([]Job, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store %s: %w", s.path, err)
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("problem loading job store %s: %w", s.path, err)
+	}
+	return jobs, nil
+}
+
+func (s *FileStore) Save(jobs []Job) error {
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,523 @@
+package reflects
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// DeepCopy 返回 v 的深拷贝：导出字段以及指针、切片、map、接口指向的值都会被复制。
+// 同一个指针/map/切片在副本中仍然是共享的，因此带环的结构也能正确复制。
+// 未导出字段只能随结构体整体赋值，与原值共享；没有导出字段的结构体（例如 time.Location、
+// sync.Mutex、reflect.Type 背后的类型描述）内部状态由其所在的包维护，指向它们的指针直接共享。
+// 函数和 channel 无法复制，副本与原值共享。
+func DeepCopy[T any](v T) T {
+	var out T
+	c := newCopier()
+	c.copy(reflect.ValueOf(&out).Elem(), reflect.ValueOf(&v).Elem())
+	return out
+}
+
+// MapReport Map 的结果，路径使用 dst/src 中的字段名，例如 Address.City、Items[0].Price
+type MapReport struct {
+	// Missing dst 中在 src 里找不到对应字段的字段
+	Missing []string
+	// Unused src 中没有被 dst 使用的字段
+	Unused []string
+	// Incompatible 名字匹配但类型无法转换的字段，附带原因
+	Incompatible []string
+}
+
+// OK 所有 dst 字段都找到了可以转换的来源
+func (r MapReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Incompatible) == 0
+}
+
+// Map 把 src 中的字段复制到 dst 指向的结构体中，常用于 DTO 和领域对象之间的转换。
+//
+// 字段按名字匹配，依次尝试：字段名、json tag 中的名字、忽略大小写的字段名，嵌入结构体的字段会被提升。
+// 类型不同时会尝试转换：数值之间（溢出或丢失小数视为不兼容）、底层类型相同的命名类型、
+// string 与 []byte、指针与非指针、以及元素可以转换的切片/数组/map 和嵌套结构体。
+// 复制是深拷贝，dst 不会与 src 共享任何可变数据。
+func Map(dst, src any) (MapReport, error) {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return MapReport{}, fmt.Errorf("Map expects a non-nil pointer as dst, got %T", dst)
+	}
+	sv := reflect.ValueOf(src)
+	for sv.Kind() == reflect.Pointer {
+		if sv.IsNil() {
+			return MapReport{}, fmt.Errorf("Map expects a non-nil src, got %T", src)
+		}
+		sv = sv.Elem()
+	}
+
+	c := newCopier()
+	c.report = &MapReport{}
+	if err := c.convert(dv.Elem(), sv, ""); err != nil {
+		return MapReport{}, err
+	}
+	return *c.report, nil
+}
+
+type copier struct {
+	// seen 已经复制过的引用类型，键为源值的地址和目标类型，保证共享和环在副本中保持不变
+	seen   map[copyKey]reflect.Value
+	report *MapReport
+}
+
+type copyKey struct {
+	ptr uintptr
+	len int
+	src reflect.Type
+	dst reflect.Type
+}
+
+func newCopier() *copier {
+	return &copier{seen: map[copyKey]reflect.Value{}}
+}
+
+// copy 深拷贝同类型的值，dst 必须可以 Set
+func (c *copier) copy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() || opaque(src.Type().Elem()) {
+			dst.Set(src)
+			return
+		}
+		key := copyKey{ptr: src.Pointer(), src: src.Type(), dst: dst.Type()}
+		if p, ok := c.seen[key]; ok {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		c.seen[key] = p
+		c.copy(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		elem := reflect.New(src.Elem().Type()).Elem()
+		c.copy(elem, src.Elem())
+		dst.Set(elem)
+	case reflect.Struct:
+		// 未导出字段随整体赋值浅拷贝，导出字段再逐个深拷贝
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).IsExported() {
+				c.copy(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		key := copyKey{ptr: src.Pointer(), len: src.Len(), src: src.Type(), dst: dst.Type()}
+		if s, ok := c.seen[key]; ok {
+			dst.Set(s)
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		c.seen[key] = s
+		for i := 0; i < src.Len(); i++ {
+			c.copy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		key := copyKey{ptr: src.Pointer(), src: src.Type(), dst: dst.Type()}
+		if m, ok := c.seen[key]; ok {
+			dst.Set(m)
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.seen[key] = m
+		iter := src.MapRange()
+		for iter.Next() {
+			k := reflect.New(src.Type().Key()).Elem()
+			c.copy(k, iter.Key())
+			v := reflect.New(src.Type().Elem()).Elem()
+			c.copy(v, iter.Value())
+			m.SetMapIndex(k, v)
+		}
+		dst.Set(m)
+	default:
+		dst.Set(src)
+	}
+}
+
+// convert 把 src 转换为 dst 的类型后写入，无法转换时返回 incompatibleError
+func (c *copier) convert(dst, src reflect.Value, path string) error {
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if src.Type() == dst.Type() {
+		c.copy(dst, src)
+		return nil
+	}
+
+	switch {
+	case src.Kind() == reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return c.convert(dst, src.Elem(), path)
+	case dst.Kind() == reflect.Interface:
+		if !src.Type().AssignableTo(dst.Type()) {
+			return incompatible(dst, src)
+		}
+		elem := reflect.New(src.Type()).Elem()
+		c.copy(elem, src)
+		dst.Set(elem)
+		return nil
+	case src.Kind() == reflect.Pointer:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if dst.Kind() != reflect.Pointer {
+			return c.convert(dst, src.Elem(), path)
+		}
+		key := copyKey{ptr: src.Pointer(), src: src.Type(), dst: dst.Type()}
+		if p, ok := c.seen[key]; ok {
+			dst.Set(p)
+			return nil
+		}
+		p := reflect.New(dst.Type().Elem())
+		c.seen[key] = p
+		if err := c.convert(p.Elem(), src.Elem(), path); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	case dst.Kind() == reflect.Pointer:
+		p := reflect.New(dst.Type().Elem())
+		if err := c.convert(p.Elem(), src, path); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		if src.Kind() != reflect.Struct {
+			return incompatible(dst, src)
+		}
+		c.mapStruct(dst, src, path)
+		return nil
+	case reflect.Slice:
+		if src.Kind() == reflect.String && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(src.String()))
+			return nil
+		}
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			return incompatible(dst, src)
+		}
+		var key copyKey
+		if src.Kind() == reflect.Slice {
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dst.Type()))
+				return nil
+			}
+			// 与指针和 map 一样记录转换过的切片，元素引用回切片自身时不会无限递归
+			key = copyKey{ptr: src.Pointer(), len: src.Len(), src: src.Type(), dst: dst.Type()}
+			if s, ok := c.seen[key]; ok {
+				dst.Set(s)
+				return nil
+			}
+		}
+		s := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		if src.Kind() == reflect.Slice {
+			c.seen[key] = s
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := c.convert(s.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+	case reflect.Array:
+		if (src.Kind() != reflect.Slice && src.Kind() != reflect.Array) || src.Len() > dst.Len() {
+			return incompatible(dst, src)
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := c.convert(dst.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if src.Kind() != reflect.Map {
+			return incompatible(dst, src)
+		}
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			kpath := fmt.Sprintf("%s[%s]", path, formatKey(iter.Key()))
+			k := reflect.New(dst.Type().Key()).Elem()
+			if err := c.convert(k, iter.Key(), kpath); err != nil {
+				return err
+			}
+			v := reflect.New(dst.Type().Elem()).Elem()
+			if err := c.convert(v, iter.Value(), kpath); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+		}
+		dst.Set(m)
+		return nil
+	case reflect.String:
+		switch {
+		case src.Kind() == reflect.String:
+			dst.SetString(src.String())
+		case src.Kind() == reflect.Slice && src.Type().Elem().Kind() == reflect.Uint8:
+			dst.SetString(string(src.Bytes()))
+		default:
+			return incompatible(dst, src)
+		}
+		return nil
+	case reflect.Bool:
+		if src.Kind() != reflect.Bool {
+			return incompatible(dst, src)
+		}
+		dst.SetBool(src.Bool())
+		return nil
+	}
+	return convertNumber(dst, src)
+}
+
+// convertNumber 在数值类型之间转换，溢出、负数转无符号以及丢失小数部分都视为不兼容
+func convertNumber(dst, src reflect.Value) error {
+	var (
+		f        float64
+		integral bool
+	)
+	switch {
+	case src.CanInt():
+		f, integral = float64(src.Int()), true
+	case src.CanUint():
+		f, integral = float64(src.Uint()), true
+	case src.CanFloat():
+		f = src.Float()
+	default:
+		return incompatible(dst, src)
+	}
+
+	switch {
+	case dst.CanInt():
+		var n int64
+		switch {
+		case src.CanInt():
+			n = src.Int()
+		case src.CanUint():
+			if src.Uint() > math.MaxInt64 {
+				return incompatible(dst, src)
+			}
+			n = int64(src.Uint())
+		default:
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return incompatible(dst, src)
+			}
+			n = int64(f)
+		}
+		if dst.OverflowInt(n) {
+			return incompatible(dst, src)
+		}
+		dst.SetInt(n)
+	case dst.CanUint():
+		var n uint64
+		switch {
+		case src.CanUint():
+			n = src.Uint()
+		case src.CanInt():
+			if src.Int() < 0 {
+				return incompatible(dst, src)
+			}
+			n = uint64(src.Int())
+		default:
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return incompatible(dst, src)
+			}
+			n = uint64(f)
+		}
+		if dst.OverflowUint(n) {
+			return incompatible(dst, src)
+		}
+		dst.SetUint(n)
+	case dst.CanFloat():
+		if !integral && dst.OverflowFloat(f) {
+			return incompatible(dst, src)
+		}
+		dst.SetFloat(f)
+	default:
+		return incompatible(dst, src)
+	}
+	return nil
+}
+
+// incompatibleError 某个值无法转换为目标类型，由 mapStruct 记录到 MapReport.Incompatible
+type incompatibleError struct {
+	dst, src reflect.Type
+}
+
+func (e *incompatibleError) Error() string {
+	return fmt.Sprintf("cannot convert %s to %s", e.src, e.dst)
+}
+
+func incompatible(dst, src reflect.Value) error {
+	return &incompatibleError{dst: dst.Type(), src: src.Type()}
+}
+
+// mapStruct 按名字匹配字段，单个字段失败只记录到报告中，不影响其它字段
+func (c *copier) mapStruct(dst, src reflect.Value, path string) {
+	plan := structPlanFor(dst.Type(), src.Type())
+	for _, f := range plan.fields {
+		fpath := joinPath(path, f.path)
+		if f.src == nil {
+			c.report.Missing = append(c.report.Missing, fpath)
+			continue
+		}
+		if err := c.convert(dst.FieldByIndex(f.dst), src.FieldByIndex(f.src), fpath); err != nil {
+			c.report.Incompatible = append(c.report.Incompatible, fmt.Sprintf("%s: %v", fpath, err))
+		}
+	}
+	for _, unused := range plan.unused {
+		c.report.Unused = append(c.report.Unused, joinPath(path, unused))
+	}
+}
+
+// structPlans 缓存两个结构体类型之间的字段对应关系，避免每次 Map 都重新匹配名字
+var structPlans sync.Map // map[[2]reflect.Type]*structPlan
+
+type structPlan struct {
+	// fields 按 dst 字段顺序排列，src 为 nil 表示找不到对应的字段
+	fields []fieldPlan
+	unused []string
+}
+
+type fieldPlan struct {
+	dst, src []int
+	path     string
+}
+
+func structPlanFor(dt, st reflect.Type) *structPlan {
+	key := [2]reflect.Type{dt, st}
+	if plan, ok := structPlans.Load(key); ok {
+		return plan.(*structPlan)
+	}
+
+	srcFields := mappableFields(st)
+	byName := map[string]int{}
+	// 精确匹配优先于忽略大小写的匹配
+	for i, f := range srcFields {
+		for _, name := range f.names {
+			if _, ok := byName[strings.ToLower(name)]; !ok {
+				byName[strings.ToLower(name)] = i
+			}
+		}
+	}
+	for i, f := range srcFields {
+		for _, name := range f.names {
+			byName[name] = i
+		}
+	}
+
+	plan := &structPlan{}
+	used := make([]bool, len(srcFields))
+	for _, df := range mappableFields(dt) {
+		si, ok := -1, false
+		for _, name := range df.names {
+			if si, ok = byName[name]; ok {
+				break
+			}
+		}
+		if !ok {
+			for _, name := range df.names {
+				if si, ok = byName[strings.ToLower(name)]; ok {
+					break
+				}
+			}
+		}
+		f := fieldPlan{dst: df.index, path: df.path}
+		if ok {
+			f.src = srcFields[si].index
+			used[si] = true
+		}
+		plan.fields = append(plan.fields, f)
+	}
+	for i, f := range srcFields {
+		if !used[i] {
+			plan.unused = append(plan.unused, f.path)
+		}
+	}
+
+	actual, _ := structPlans.LoadOrStore(key, plan)
+	return actual.(*structPlan)
+}
+
+type mappableField struct {
+	// names 候选名字：字段名以及 json tag 中的名字
+	names []string
+	path  string
+	index []int
+}
+
+// mappableFields 返回结构体的导出字段，非指针的嵌入结构体会被展开
+func mappableFields(t reflect.Type) []mappableField {
+	var out []mappableField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && !hasJSONName(sf) {
+			for _, f := range mappableFields(sf.Type) {
+				f.index = append([]int{i}, f.index...)
+				f.path = sf.Name + "." + f.path
+				out = append(out, f)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		names := []string{sf.Name}
+		if key, ok := fieldKey(sf); !ok {
+			continue
+		} else if key != sf.Name {
+			names = append(names, key)
+		}
+		out = append(out, mappableField{names: names, path: sf.Name, index: []int{i}})
+	}
+	return out
+}
+
+// opaque 判断 t 是否为没有导出字段的结构体，这类值只能整体赋值或共享，不能逐字段复制
+func opaque(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
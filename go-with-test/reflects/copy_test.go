@@ -0,0 +1,328 @@
+package reflects
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	Street string
+	City   string
+}
+
+type Audit struct {
+	CreatedAt time.Time
+	CreatedBy string
+}
+
+// Customer 领域对象
+type Customer struct {
+	Audit
+	ID       int64
+	Name     string
+	Email    string
+	Address  *Address
+	Tags     []string
+	Scores   map[string]float64
+	Orders   []Order
+	Referrer *Customer
+	Status   CustomerStatus
+	mu       sync.Mutex
+	notes    []string
+}
+
+type Order struct {
+	ID    int64
+	Total float64
+	Items []string
+}
+
+type CustomerStatus string
+
+// CustomerDTO 对外输出的结构，字段名、类型与 Customer 不完全一致
+type CustomerDTO struct {
+	ID        int32             `json:"id"`
+	FullName  string            `json:"name"`
+	Email     []byte            `json:"email"`
+	Address   AddressDTO        `json:"address"`
+	Tags      [3]string         `json:"tags"`
+	Scores    map[string]int    `json:"scores"`
+	Orders    []*OrderDTO       `json:"orders"`
+	Status    string            `json:"status"`
+	CreatedBy string            `json:"createdBy"`
+	Phone     string            `json:"phone"`
+	Extra     map[string]string `json:"extra"`
+}
+
+type AddressDTO struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type OrderDTO struct {
+	ID    uint `json:"id"`
+	Total int  `json:"total"`
+	Items []string
+}
+
+func newCustomer() *Customer {
+	c := &Customer{
+		Audit:   Audit{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), CreatedBy: "admin"},
+		ID:      42,
+		Name:    "Chris",
+		Email:   "chris@example.com",
+		Address: &Address{Street: "1 Main St", City: "London"},
+		Tags:    []string{"vip", "beta"},
+		Scores:  map[string]float64{"credit": 700, "risk": 0.5},
+		Orders:  []Order{{ID: 1, Total: 9.5, Items: []string{"book"}}, {ID: 2, Total: 20, Items: []string{"pen", "ink"}}},
+		Status:  "active",
+		notes:   []string{"private"},
+	}
+	c.Referrer = c
+	return c
+}
+
+func TestDeepCopy(t *testing.T) {
+	src := newCustomer()
+	dst := DeepCopy(src)
+
+	require.NotSame(t, src, dst)
+	assert.Equal(t, src.Name, dst.Name)
+	assert.Equal(t, src.CreatedAt, dst.CreatedAt)
+	assert.Equal(t, []string{"private"}, dst.notes, "unexported fields are assigned")
+	assert.Same(t, dst, dst.Referrer, "cycles are preserved in the copy")
+	assert.Empty(t, Diff(src, dst))
+
+	dst.Address.City = "Paris"
+	dst.Tags[0] = "changed"
+	dst.Scores["credit"] = 0
+	dst.Orders[0].Items[0] = "changed"
+
+	assert.Equal(t, "London", src.Address.City)
+	assert.Equal(t, "vip", src.Tags[0])
+	assert.Equal(t, 700.0, src.Scores["credit"])
+	assert.Equal(t, "book", src.Orders[0].Items[0])
+
+	t.Run("interfaces and shared values", func(t *testing.T) {
+		shared := &Address{City: "London"}
+		src := map[string]any{"a": shared, "b": shared, "list": []any{1, "x", Address{City: "Paris"}}}
+		dst := DeepCopy(src)
+
+		assert.Equal(t, src, dst)
+		assert.NotSame(t, shared, dst["a"])
+		assert.Same(t, dst["a"], dst["b"])
+	})
+
+	t.Run("types that manage their own state", func(t *testing.T) {
+		type handles struct {
+			Type     reflect.Type
+			Location *time.Location
+			Time     time.Time
+			Mu       *sync.Mutex
+		}
+		loc := time.FixedZone("CST", 8*3600)
+		src := handles{Type: reflect.TypeOf(Customer{}), Location: loc, Time: time.Now().In(loc), Mu: &sync.Mutex{}}
+		src.Mu.Lock()
+		dst := DeepCopy(src)
+
+		assert.Equal(t, reflect.TypeOf(Customer{}), dst.Type)
+		assert.True(t, dst.Type == reflect.TypeOf(Customer{}), "type identity is kept")
+		assert.Same(t, loc, dst.Location)
+		assert.Same(t, loc, dst.Time.Location())
+		assert.True(t, src.Time.Equal(dst.Time))
+		assert.Same(t, src.Mu, dst.Mu)
+		dst.Mu.Unlock()
+		assert.True(t, src.Mu.TryLock())
+	})
+
+	t.Run("nil values", func(t *testing.T) {
+		assert.Nil(t, DeepCopy[*Customer](nil))
+		assert.Nil(t, DeepCopy[any](nil))
+		assert.Nil(t, DeepCopy(Customer{}).Tags)
+	})
+}
+
+func TestMap(t *testing.T) {
+	src := newCustomer()
+	var dto CustomerDTO
+	report, err := Map(&dto, src)
+	require.NoError(t, err)
+
+	assert.Equal(t, CustomerDTO{
+		ID:        42,
+		FullName:  "Chris",
+		Email:     []byte("chris@example.com"),
+		Address:   AddressDTO{City: "London"},
+		Tags:      [3]string{"vip", "beta"},
+		Scores:    nil,
+		Orders:    []*OrderDTO{{ID: 1, Total: 0, Items: []string{"book"}}, {ID: 2, Total: 20, Items: []string{"pen", "ink"}}},
+		Status:    "active",
+		CreatedBy: "admin",
+	}, dto)
+
+	assert.Equal(t, []string{"Address.Zip", "Phone", "Extra"}, report.Missing)
+	assert.Equal(t, []string{
+		"Scores: cannot convert float64 to int",
+		"Orders[0].Total: cannot convert float64 to int",
+	}, report.Incompatible)
+	assert.Equal(t, []string{"Address.Street", "Audit.CreatedAt", "Referrer"}, report.Unused)
+	assert.False(t, report.OK())
+
+	dto.Orders[0].Items[0] = "changed"
+	assert.Equal(t, "book", src.Orders[0].Items[0], "Map makes a deep copy")
+
+	t.Run("round trip", func(t *testing.T) {
+		var back Customer
+		report, err := Map(&back, &dto)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), back.ID)
+		assert.Equal(t, "chris@example.com", back.Email)
+		assert.Equal(t, CustomerStatus("active"), back.Status)
+		require.NotNil(t, back.Address)
+		assert.Equal(t, "London", back.Address.City)
+		assert.Equal(t, []string{"vip", "beta", ""}, back.Tags)
+		assert.Contains(t, report.Missing, "Referrer")
+	})
+
+	t.Run("json tag and case-insensitive matching", func(t *testing.T) {
+		var out struct {
+			Name  string
+			EMAIL string
+		}
+		report, err := Map(&out, CustomerDTO{FullName: "Chris", Email: []byte("c@x")})
+		require.NoError(t, err)
+		assert.Equal(t, "Chris", out.Name)
+		assert.Equal(t, "c@x", out.EMAIL)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("numeric conversions", func(t *testing.T) {
+		var out struct {
+			A int8
+			B uint
+			C float32
+			D int
+		}
+		report, err := Map(&out, struct {
+			A int64
+			B int
+			C int
+			D float64
+		}{A: 1000, B: -1, C: 3, D: 2})
+		require.NoError(t, err)
+		assert.Equal(t, float32(3), out.C)
+		assert.Equal(t, 2, out.D)
+		assert.Equal(t, []string{
+			"A: cannot convert int64 to int8",
+			"B: cannot convert int to uint",
+		}, report.Incompatible)
+	})
+
+	t.Run("cyclic slices", func(t *testing.T) {
+		type srcTree struct{ Children []srcTree }
+		type dstTree struct{ Children []dstTree }
+		nodes := make([]srcTree, 1)
+		nodes[0].Children = nodes
+
+		var out dstTree
+		report, err := Map(&out, srcTree{Children: nodes})
+		require.NoError(t, err)
+		assert.True(t, report.OK())
+		require.Len(t, out.Children, 1)
+		assert.Same(t, &out.Children[0], &out.Children[0].Children[0], "the cycle is preserved")
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, err := Map(CustomerDTO{}, src)
+		assert.Error(t, err)
+		_, err = Map(&dto, (*Customer)(nil))
+		assert.Error(t, err)
+	})
+}
+
+func copyCustomerByHand(c *Customer) *Customer {
+	out := &Customer{
+		Audit:  c.Audit,
+		ID:     c.ID,
+		Name:   c.Name,
+		Email:  c.Email,
+		Status: c.Status,
+	}
+	if c.Address != nil {
+		addr := *c.Address
+		out.Address = &addr
+	}
+	out.Tags = append([]string(nil), c.Tags...)
+	out.Scores = make(map[string]float64, len(c.Scores))
+	for k, v := range c.Scores {
+		out.Scores[k] = v
+	}
+	out.Orders = make([]Order, len(c.Orders))
+	for i, o := range c.Orders {
+		out.Orders[i] = Order{ID: o.ID, Total: o.Total, Items: append([]string(nil), o.Items...)}
+	}
+	if c.Referrer == c {
+		out.Referrer = out
+	}
+	out.notes = c.notes
+	return out
+}
+
+func mapCustomerByHand(c *Customer) CustomerDTO {
+	dto := CustomerDTO{
+		ID:        int32(c.ID),
+		FullName:  c.Name,
+		Email:     []byte(c.Email),
+		Status:    string(c.Status),
+		CreatedBy: c.CreatedBy,
+	}
+	if c.Address != nil {
+		dto.Address.City = c.Address.City
+	}
+	copy(dto.Tags[:], c.Tags)
+	dto.Orders = make([]*OrderDTO, len(c.Orders))
+	for i, o := range c.Orders {
+		dto.Orders[i] = &OrderDTO{ID: uint(o.ID), Items: append([]string(nil), o.Items...)}
+	}
+	return dto
+}
+
+func BenchmarkDeepCopy(b *testing.B) {
+	c := newCustomer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DeepCopy(c)
+	}
+}
+
+func BenchmarkDeepCopyByHand(b *testing.B) {
+	c := newCustomer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		copyCustomerByHand(c)
+	}
+}
+
+func BenchmarkMap(b *testing.B) {
+	c := newCustomer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dto CustomerDTO
+		if _, err := Map(&dto, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMapByHand(b *testing.B) {
+	c := newCustomer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mapCustomerByHand(c)
+	}
+}
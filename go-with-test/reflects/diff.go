@@ -0,0 +1,292 @@
+package reflects
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ChangeType 变更的类型
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// Change Diff 发现的一处差异
+type Change struct {
+	Type ChangeType
+	// Path 与 Walk 的路径格式相同，例如 a.b[3].c
+	Path string
+	// Pointer 同一位置的 RFC 6901 JSON Pointer，使用 json tag 中的名字，例如 /a/b/3/c
+	Pointer string
+	From    any
+	To      any
+
+	// omitted 为 true 表示该字段在 JSON 中会因为 omitempty 被省略
+	fromOmitted, toOmitted bool
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatDiffValue(c.To))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, formatDiffValue(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s => %s", c.Path, formatDiffValue(c.From), formatDiffValue(c.To))
+	}
+}
+
+// Changes Diff 的结果
+type Changes []Change
+
+// String 每行一处差异，适合直接放进测试失败信息：
+//
+//	if d := reflects.Diff(want, got); len(d) > 0 {
+//		t.Errorf("config mismatch (-want +got):\n%s", d)
+//	}
+func (cs Changes) String() string {
+	lines := make([]string, len(cs))
+	for i, c := range cs {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff 比较 a 和 b，返回把 a 变成 b 需要的变更，a、b 相同时返回空。
+//
+// 结构体按导出字段比较（与 encoding/json 一致，忽略 json:"-" 字段），切片按下标比较，
+// 多出的元素记为 Added/Removed，map 按键排序后比较；[]byte、类型不同的值以及带 Equal 方法的类型
+// （例如 time.Time）作为整体比较。切片中的删除按下标从大到小排列，因此可以按顺序应用。
+func Diff(a, b any) Changes {
+	d := &differ{visiting: map[visitPair]bool{}}
+	d.diff(reflect.ValueOf(a), reflect.ValueOf(b), "", "")
+	return d.changes
+}
+
+type differ struct {
+	changes Changes
+	// visiting 当前递归路径上正在比较的指针对
+	visiting map[visitPair]bool
+}
+
+type visitPair struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+func (d *differ) add(typ ChangeType, path, pointer string, from, to reflect.Value) {
+	d.changes = append(d.changes, Change{Type: typ, Path: path, Pointer: pointer, From: valueOrNil(from), To: valueOrNil(to)})
+}
+
+func (d *differ) diff(a, b reflect.Value, path, pointer string) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.add(Modified, path, pointer, a, b)
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.add(Modified, path, pointer, a, b)
+		return
+	}
+	if eq, ok := callEqual(a, b); ok {
+		if !eq {
+			d.add(Modified, path, pointer, a, b)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(Modified, path, pointer, a, b)
+			}
+			return
+		}
+		if a.Kind() == reflect.Pointer {
+			// 与 Walk 一样只记录当前递归路径上的指针对，只有真正的环才会被截断；
+			// 多处共享的指针在每个位置都要比较，否则生成的 JSON Patch 会漏掉后面的位置
+			pair := visitPair{a.Pointer(), b.Pointer(), a.Type()}
+			if d.visiting[pair] {
+				return
+			}
+			d.visiting[pair] = true
+			defer delete(d.visiting, pair)
+		}
+		d.diff(a.Elem(), b.Elem(), path, pointer)
+	case reflect.Struct:
+		d.diffStruct(a, b, path, pointer)
+	case reflect.Slice:
+		if a.Type().Elem().Kind() == reflect.Uint8 || a.IsNil() != b.IsNil() {
+			if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				d.add(Modified, path, pointer, a, b)
+			}
+			return
+		}
+		d.diffList(a, b, path, pointer)
+	case reflect.Array:
+		d.diffList(a, b, path, pointer)
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			d.add(Modified, path, pointer, a, b)
+			return
+		}
+		d.diffMap(a, b, path, pointer)
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		// 无法有意义地比较
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			d.add(Modified, path, pointer, a, b)
+		}
+	}
+}
+
+func (d *differ) diffStruct(a, b reflect.Value, path, pointer string) {
+	typ := a.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		name, omitEmpty, ok := jsonField(sf)
+		if !ok {
+			continue
+		}
+		fa, fb := a.Field(i), b.Field(i)
+		fpath, fpointer := joinPath(path, sf.Name), pointer+"/"+escapePointer(name)
+		if sf.Anonymous && !hasJSONName(sf) && isNestedStruct(sf.Type) {
+			// encoding/json 会把嵌入结构体的字段提升到外层
+			fpointer = pointer
+		}
+
+		start := len(d.changes)
+		d.diff(fa, fb, fpath, fpointer)
+		if omitEmpty && start < len(d.changes) {
+			c := &d.changes[len(d.changes)-1]
+			if c.Pointer == fpointer {
+				c.fromOmitted, c.toOmitted = isEmptyJSONValue(fa), isEmptyJSONValue(fb)
+			}
+		}
+	}
+}
+
+func (d *differ) diffList(a, b reflect.Value, path, pointer string) {
+	n := min(a.Len(), b.Len())
+	for i := 0; i < n; i++ {
+		idx := strconv.Itoa(i)
+		d.diff(a.Index(i), b.Index(i), path+"["+idx+"]", pointer+"/"+idx)
+	}
+	for i := n; i < b.Len(); i++ {
+		idx := strconv.Itoa(i)
+		d.add(Added, path+"["+idx+"]", pointer+"/"+idx, reflect.Value{}, b.Index(i))
+	}
+	for i := a.Len() - 1; i >= n; i-- {
+		idx := strconv.Itoa(i)
+		d.add(Removed, path+"["+idx+"]", pointer+"/"+idx, a.Index(i), reflect.Value{})
+	}
+}
+
+func (d *differ) diffMap(a, b reflect.Value, path, pointer string) {
+	keys := a.MapKeys()
+	for _, k := range b.MapKeys() {
+		if !a.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, compareValues)
+
+	for _, k := range keys {
+		key := formatKey(k)
+		kpath, kpointer := path+"["+key+"]", pointer+"/"+escapePointer(key)
+		va, vb := a.MapIndex(k), b.MapIndex(k)
+		switch {
+		case !va.IsValid():
+			d.add(Added, kpath, kpointer, reflect.Value{}, vb)
+		case !vb.IsValid():
+			d.add(Removed, kpath, kpointer, va, reflect.Value{})
+		default:
+			d.diff(va, vb, kpath, kpointer)
+		}
+	}
+}
+
+// callEqual 对定义了 func (T) Equal(T) bool 方法的类型（例如 time.Time、net.IP）使用该方法比较
+func callEqual(a, b reflect.Value) (bool, bool) {
+	if !a.CanInterface() {
+		return false, false
+	}
+	m, ok := a.Type().MethodByName("Equal")
+	if !ok || m.Type.NumIn() != 2 || m.Type.In(1) != a.Type() || m.Type.NumOut() != 1 || m.Type.Out(0).Kind() != reflect.Bool {
+		return false, false
+	}
+	if (a.Kind() == reflect.Pointer || a.Kind() == reflect.Interface) && (a.IsNil() || b.IsNil()) {
+		return a.IsNil() && b.IsNil(), true
+	}
+	return m.Func.Call([]reflect.Value{a, b})[0].Bool(), true
+}
+
+// jsonField 返回字段在 JSON 中的名字以及是否带 omitempty，未导出或 json:"-" 的字段返回 false
+func jsonField(sf reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	name, ok = fieldKey(sf)
+	if !ok {
+		return "", false, false
+	}
+	_, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// isEmptyJSONValue 与 encoding/json 判断 omitempty 的规则一致
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// escapePointer 按 RFC 6901 转义 JSON Pointer 中的一段
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func valueOrNil(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func formatDiffValue(x any) string {
+	switch v := x.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return strconv.Quote(v)
+	case fmt.Stringer, error:
+		return fmt.Sprint(v)
+	}
+	rv := reflect.ValueOf(x)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		return "&" + formatDiffValue(rv.Elem().Interface())
+	}
+	if data, err := json.Marshal(x); err == nil && (rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", x)
+}
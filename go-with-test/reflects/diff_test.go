@@ -0,0 +1,212 @@
+package reflects
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Upstream struct {
+	Host   string `json:"host"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+type serviceConfig struct {
+	Common
+	Name      string              `json:"name"`
+	Upstreams []Upstream          `json:"upstreams"`
+	Labels    map[string]string   `json:"labels"`
+	Limits    map[string][]int    `json:"limits,omitempty"`
+	Timeout   *time.Duration      `json:"timeout"`
+	Updated   time.Time           `json:"updated"`
+	Extra     any                 `json:"extra"`
+	Internal  string              `json:"-"`
+	Routes    map[string]Upstream `json:"routes/v1"`
+}
+
+func duration(d time.Duration) *time.Duration { return &d }
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := serviceConfig{
+		Common:    Common{Env: "dev"},
+		Name:      "api",
+		Upstreams: []Upstream{{Host: "a"}, {Host: "b", Weight: 2}, {Host: "c"}},
+		Labels:    map[string]string{"team": "core", "tier": "1"},
+		Timeout:   duration(time.Second),
+		Updated:   now,
+		Extra:     1,
+		Internal:  "x",
+	}
+	b := a
+	b.Env = "prod"
+	b.Upstreams = []Upstream{{Host: "a", Weight: 5}, {Host: "b"}}
+	b.Labels = map[string]string{"team": "core", "owner": "ops"}
+	b.Limits = map[string][]int{"qps": {100}}
+	b.Timeout = duration(2 * time.Second)
+	b.Updated = now.In(time.FixedZone("CST", 8*3600))
+	b.Extra = "one"
+	b.Internal = "y"
+
+	changes := Diff(a, b)
+	assert.Equal(t, `~ Common.Env: "dev" => "prod"
+~ Upstreams[0].Weight: 0 => 5
+~ Upstreams[1].Weight: 2 => 0
+- Upstreams[2]: {"host":"c"}
++ Labels[owner]: "ops"
+- Labels[tier]: "1"
+~ Limits: null => {"qps":[100]}
+~ Timeout: 1s => 2s
+~ Extra: 1 => "one"`, changes.String())
+
+	assert.Equal(t, "/env", changes[0].Pointer)
+	assert.Equal(t, "/upstreams/2", changes[3].Pointer)
+	assert.Empty(t, Diff(a, a))
+	assert.Empty(t, Diff(&a, &a))
+	assert.Empty(t, Diff(nil, nil))
+	assert.Len(t, Diff(nil, a), 1)
+
+	t.Run("cyclic values", func(t *testing.T) {
+		x := &treeNode{Name: "x"}
+		x.Parent = x
+		y := &treeNode{Name: "y"}
+		y.Parent = y
+		assert.Equal(t, `~ Name: "x" => "y"`, Diff(x, y).String())
+	})
+}
+
+func TestJSONPatchRoundTrip(t *testing.T) {
+	a := serviceConfig{
+		Name:      "api",
+		Upstreams: []Upstream{{Host: "a"}, {Host: "b", Weight: 2}, {Host: "c"}},
+		Labels:    map[string]string{"team": "core", "tier": "1"},
+		Routes:    map[string]Upstream{"/users": {Host: "a"}},
+	}
+	b := a
+	b.Name = "gateway"
+	b.Upstreams = []Upstream{{Host: "a", Weight: 5}}
+	b.Labels = map[string]string{"team": "core", "owner": "ops"}
+	b.Limits = map[string][]int{"qps": {100}}
+	b.Routes = map[string]Upstream{"/users": {Host: "b"}, "/orders~": {Host: "c"}}
+	b.Timeout = duration(time.Minute)
+
+	patch, err := Diff(a, b).JSONPatch()
+	require.NoError(t, err)
+
+	var ops []PatchOperation
+	require.NoError(t, json.Unmarshal(patch, &ops))
+	assert.Contains(t, ops, PatchOperation{Op: "add", Path: "/upstreams/0/weight", Value: json.RawMessage("5")})
+	// 切片的删除从后往前，按顺序应用时下标不会错位
+	assert.Subset(t, ops, []PatchOperation{{Op: "remove", Path: "/upstreams/2"}, {Op: "remove", Path: "/upstreams/1"}})
+	assert.Equal(t, PatchOperation{Op: "remove", Path: "/upstreams/1"}, ops[3])
+	assert.Contains(t, ops, PatchOperation{Op: "add", Path: "/limits", Value: json.RawMessage(`{"qps":[100]}`)})
+	assert.Contains(t, ops, PatchOperation{Op: "add", Path: "/routes~1v1/~1orders~0", Value: json.RawMessage(`{"host":"c"}`)})
+
+	got := a
+	require.NoError(t, Patch(&got, patch))
+	assert.Empty(t, Diff(b, got).String())
+
+	docA, _ := json.Marshal(a)
+	docB, _ := json.Marshal(b)
+	patched, err := ApplyPatch(docA, patch)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(docB), string(patched))
+}
+
+type failover struct {
+	Primary *Upstream `json:"primary"`
+	Backup  *Upstream `json:"backup"`
+}
+
+// 共享的指针在每个位置都要产生变更，应用到 a 上才能得到 b
+func TestJSONPatchSharedPointers(t *testing.T) {
+	ua, ub := &Upstream{Host: "a"}, &Upstream{Host: "b", Weight: 2}
+	a := failover{Primary: ua, Backup: ua}
+	b := failover{Primary: ub, Backup: ub}
+
+	changes := Diff(a, b)
+	assert.Equal(t, `~ Primary.Host: "a" => "b"
+~ Primary.Weight: 0 => 2
+~ Backup.Host: "a" => "b"
+~ Backup.Weight: 0 => 2`, changes.String())
+
+	patch, err := changes.JSONPatch()
+	require.NoError(t, err)
+	docA, _ := json.Marshal(a)
+	docB, _ := json.Marshal(b)
+	patched, err := ApplyPatch(docA, patch)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(docB), string(patched))
+}
+
+// 补丁没有涉及的大整数原样保留，不会经过 float64
+func TestPatchKeepsLargeIntegers(t *testing.T) {
+	type record struct {
+		ID    int64  `json:"id"`
+		Name  string `json:"name"`
+		Count uint64 `json:"count"`
+	}
+	r := record{ID: 1<<60 + 1, Name: "a", Count: 1<<64 - 1}
+	patch := []byte(`[
+		{"op": "test", "path": "/id", "value": 1152921504606846977},
+		{"op": "replace", "path": "/name", "value": "b"},
+		{"op": "copy", "from": "/id", "path": "/copied"}
+	]`)
+
+	patched, err := ApplyPatch([]byte(`{"id": 1152921504606846977, "name": "a"}`), patch)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 1152921504606846977, "name": "b", "copied": 1152921504606846977}`, string(patched))
+	assert.Contains(t, string(patched), "1152921504606846977")
+
+	require.NoError(t, Patch(&r, []byte(`[{"op": "replace", "path": "/name", "value": "b"}]`)))
+	assert.Equal(t, record{ID: 1<<60 + 1, Name: "b", Count: 1<<64 - 1}, r)
+
+	_, err = ApplyPatch([]byte(`{"id": 1152921504606846977}`), []byte(`[{"op": "test", "path": "/id", "value": 1152921504606846976}]`))
+	assert.ErrorIs(t, err, PatchTestFailedError)
+	_, err = ApplyPatch([]byte(`{"n": 1}`), []byte(`[{"op": "test", "path": "/n", "value": 1.0}]`))
+	assert.NoError(t, err)
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := `{"a": {"b": [1, 2, 3]}, "c": "x"}`
+
+	cases := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{"add to array", `[{"op": "add", "path": "/a/b/1", "value": 9}]`, `{"a": {"b": [1, 9, 2, 3]}, "c": "x"}`, nil},
+		{"append", `[{"op": "add", "path": "/a/b/-", "value": 4}]`, `{"a": {"b": [1, 2, 3, 4]}, "c": "x"}`, nil},
+		{"add null", `[{"op": "add", "path": "/d", "value": null}]`, `{"a": {"b": [1, 2, 3]}, "c": "x", "d": null}`, nil},
+		{"remove", `[{"op": "remove", "path": "/a/b/0"}]`, `{"a": {"b": [2, 3]}, "c": "x"}`, nil},
+		{"replace", `[{"op": "replace", "path": "/c", "value": {"y": 1}}]`, `{"a": {"b": [1, 2, 3]}, "c": {"y": 1}}`, nil},
+		{"replace root", `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`, nil},
+		{"move", `[{"op": "move", "from": "/a/b", "path": "/b"}]`, `{"a": {}, "b": [1, 2, 3], "c": "x"}`, nil},
+		{"copy", `[{"op": "copy", "from": "/a/b/2", "path": "/a/b/0"}]`, `{"a": {"b": [3, 1, 2, 3]}, "c": "x"}`, nil},
+		{"test passes", `[{"op": "test", "path": "/a/b", "value": [1, 2, 3]}, {"op": "remove", "path": "/c"}]`, `{"a": {"b": [1, 2, 3]}}`, nil},
+		{"test fails", `[{"op": "test", "path": "/c", "value": "y"}]`, "", PatchTestFailedError},
+		{"missing path", `[{"op": "replace", "path": "/nope", "value": 1}]`, "", PatchPathNotFoundError},
+		{"index out of range", `[{"op": "add", "path": "/a/b/5", "value": 1}]`, "", PatchPathNotFoundError},
+		{"leading zero", `[{"op": "remove", "path": "/a/b/01"}]`, "", InvalidPatchError},
+		{"move into child", `[{"op": "move", "from": "/a", "path": "/a/x"}]`, "", InvalidPatchError},
+		{"unknown op", `[{"op": "merge", "path": "/a"}]`, "", InvalidPatchError},
+		{"missing value", `[{"op": "add", "path": "/a"}]`, "", InvalidPatchError},
+		{"not a patch", `{}`, "", InvalidPatchError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ApplyPatch([]byte(doc), []byte(c.patch))
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, c.want, string(got))
+		})
+	}
+}
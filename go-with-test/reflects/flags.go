@@ -0,0 +1,193 @@
+package reflects
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"text/tabwriter"
+)
+
+// RegisterFlags 为配置结构体的每个叶子字段注册一个命令行参数，返回的 Option 传给 Load 后，
+// 显式设置过的参数会覆盖环境变量、配置文件和默认值。
+//
+// 参数名为字段的配置路径，例如 `json:"db"` 下的 `json:"port"` 对应 -db.port；
+// 说明取自 desc tag，默认值取自 default tag，只用于 -h 的展示。
+// 和 flag 包一样，ptr 不是结构体指针或参数名重复时会 panic。
+//
+//	fs := flag.NewFlagSet("app", flag.ExitOnError)
+//	flags := reflects.RegisterFlags(fs, &cfg)
+//	fs.Parse(os.Args[1:])
+//	err := reflects.Load(&cfg, reflects.WithFile("config.yaml"), flags)
+func RegisterFlags(fs *flag.FlagSet, ptr any) Option {
+	t := reflect.TypeOf(ptr)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: RegisterFlags expects a pointer to struct, got %T", ptr))
+	}
+
+	var leaves []leafField
+	collectLeaves(t.Elem(), "", "", nil, &leaves)
+
+	values := make([]*flagValue, len(leaves))
+	for i, leaf := range leaves {
+		def, _ := leaf.Field.Tag.Lookup("default")
+		values[i] = &flagValue{path: leaf.Path, typ: leaf.Field.Type, def: def}
+		fs.Var(values[i], leaf.Path, leaf.Field.Tag.Get("desc"))
+	}
+
+	return func(l *loader) {
+		for _, v := range values {
+			if !v.set {
+				continue
+			}
+			if l.flags == nil {
+				l.flags = map[string]string{}
+			}
+			l.flags[v.path] = v.value
+		}
+	}
+}
+
+// flagValue 实现 flag.Value，只记录原始字符串，由 Load 统一按优先级写入字段
+type flagValue struct {
+	path  string
+	typ   reflect.Type
+	def   string
+	value string
+	set   bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.typ == nil {
+		return ""
+	}
+	if f.set {
+		return f.value
+	}
+	return f.def
+}
+
+// Set 提前按字段类型解析一次，让非法参数在 fs.Parse 时就报错
+func (f *flagValue) Set(s string) error {
+	if err := setString(reflect.New(f.typ).Elem(), s); err != nil {
+		return err
+	}
+	f.value, f.set = s, true
+	return nil
+}
+
+// IsBoolFlag 让布尔字段可以写作 -debug 而不是 -debug=true
+func (f *flagValue) IsBoolFlag() bool {
+	t := f.typ
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// Setting 配置参考表中的一行
+type Setting struct {
+	Path    string
+	Env     string
+	Flag    string
+	Type    string
+	Default string
+	Desc    string
+	// Value 当前值，secret 字段为 ******，内部含有 secret 字段的值为 Redacted
+	Value any
+	// Source 最终值的来源，为空表示没有任何来源设置过该字段
+	Source Source
+}
+
+// Settings 加载配置并返回每个叶子字段的当前值及其来源，用于排查某个配置到底是从哪里来的
+func Settings(ptr any, opts ...Option) ([]Setting, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Settings expects a non-nil pointer to struct, got %T", ptr)
+	}
+
+	l := &loader{envPrefix: "CONFIG", lookupEnv: os.LookupEnv, sources: map[string]Source{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if err := l.load(v.Elem()); err != nil {
+		return nil, err
+	}
+
+	var leaves []leafField
+	collectLeaves(v.Elem().Type(), "", l.envPrefix, nil, &leaves)
+
+	settings := make([]Setting, len(leaves))
+	for i, leaf := range leaves {
+		s := Setting{
+			Path:    leaf.Path,
+			Env:     leaf.Env,
+			Flag:    "-" + leaf.Path,
+			Type:    leaf.Field.Type.String(),
+			Desc:    leaf.Field.Tag.Get("desc"),
+			Default: leaf.Field.Tag.Get("default"),
+			Source:  l.sources[leaf.Path],
+		}
+		// 未分配的指针结构体中的字段以及 nil 指针都视为没有值
+		if fv, err := v.Elem().FieldByIndexErr(leaf.Index); err == nil && !(fv.Kind() == reflect.Pointer && fv.IsNil()) {
+			s.Value = redactedValue(reflect.Indirect(fv))
+			if isSecret(leaf.Field) && !fv.IsZero() {
+				s.Value = redactedText
+			}
+		}
+		settings[i] = s
+	}
+	return settings, nil
+}
+
+// PrintSettings 以表格形式输出 Settings 的结果
+func PrintSettings(w io.Writer, settings []Setting) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tFLAG\tENV\tVALUE\tSOURCE\tDESCRIPTION")
+	for _, s := range settings {
+		value, source := "-", "-"
+		if s.Value != nil {
+			value = fmt.Sprint(s.Value)
+		}
+		if s.Source != "" {
+			source = string(s.Source)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Path, s.Flag, s.Env, value, source, s.Desc)
+	}
+	return tw.Flush()
+}
+
+// leafField 配置结构体中的一个叶子字段，Index 可以传给 reflect.Value.FieldByIndex
+type leafField struct {
+	Path  string
+	Env   string
+	Field reflect.StructField
+	Index []int
+}
+
+// collectLeaves 按 Load 的规则展开结构体类型，收集所有叶子字段
+func collectLeaves(t reflect.Type, path, env string, index []int, out *[]leafField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		fpath, fenv := joinPath(path, key), joinEnv(env, key)
+		if sf.Anonymous && !hasJSONName(sf) {
+			fpath, fenv = path, env
+		}
+		findex := append(append([]int(nil), index...), i)
+
+		if isNestedStruct(sf.Type) {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			collectLeaves(ft, fpath, fenv, findex, out)
+			continue
+		}
+		*out = append(*out, leafField{Path: fpath, Env: fenv, Field: sf, Index: findex})
+	}
+}
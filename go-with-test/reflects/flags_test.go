@@ -0,0 +1,132 @@
+package reflects
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flagConfig struct {
+	Common
+	Name  string     `json:"name" default:"app" desc:"application name"`
+	Debug bool       `json:"debug" desc:"enable debug mode"`
+	Port  int        `json:"port" default:"8080" desc:"port to listen on" validate:"max=65535"`
+	DB    DBConfig   `json:"db"`
+	Log   *LogConfig `json:"log"`
+}
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func TestRegisterFlags(t *testing.T) {
+	path := writeFile(t, "config.json", `{"name": "from-file", "port": 9000, "db": {"host": "db.file"}}`)
+	env := envMap(map[string]string{
+		"CONFIG_NAME":    "from-env",
+		"CONFIG_DB_HOST": "db.env",
+	})
+
+	fs := newFlagSet()
+	flags := RegisterFlags(fs, &flagConfig{})
+	require.NoError(t, fs.Parse([]string{"-name", "from-flag", "-debug", "-log.level=warn", "-db.replicas", "a,b"}))
+
+	var cfg flagConfig
+	require.NoError(t, Load(&cfg, WithFile(path), env, flags))
+
+	assert.Equal(t, "from-flag", cfg.Name, "flag beats env")
+	assert.Equal(t, "db.env", cfg.DB.Host, "env beats file")
+	assert.Equal(t, 9000, cfg.Port, "file beats default")
+	assert.Equal(t, 5432, cfg.DB.Port, "default used when nothing else is set")
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, []string{"a", "b"}, cfg.DB.Replicas)
+	require.NotNil(t, cfg.Log)
+	assert.Equal(t, "warn", cfg.Log.Level)
+
+	t.Run("invalid flag fails at parse time", func(t *testing.T) {
+		fs := newFlagSet()
+		RegisterFlags(fs, &flagConfig{})
+		assert.Error(t, fs.Parse([]string{"-port", "abc"}))
+	})
+
+	t.Run("flags are validated", func(t *testing.T) {
+		fs := newFlagSet()
+		flags := RegisterFlags(fs, &flagConfig{})
+		require.NoError(t, fs.Parse([]string{"-port", "70000"}))
+
+		var verr *ValidationError
+		assert.ErrorAs(t, Load(&flagConfig{}, envMap(nil), flags), &verr)
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		assert.Panics(t, func() { RegisterFlags(newFlagSet(), flagConfig{}) })
+	})
+}
+
+func TestRegisterFlagsUsage(t *testing.T) {
+	fs := newFlagSet()
+	RegisterFlags(fs, &flagConfig{})
+
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	usage := buf.String()
+
+	for _, want := range []string{
+		"-db.host value",
+		"(default localhost)",
+		"-env value",
+		"port to listen on (default 8080)",
+		"-log.level value",
+	} {
+		assert.Contains(t, usage, want)
+	}
+	assert.Contains(t, usage, "-debug\n", "bool flags take no value")
+}
+
+func TestSettings(t *testing.T) {
+	path := writeFile(t, "config.json", `{"port": 9000}`)
+	fs := newFlagSet()
+	flags := RegisterFlags(fs, &flagConfig{})
+	require.NoError(t, fs.Parse([]string{"-debug"}))
+
+	var cfg flagConfig
+	settings, err := Settings(&cfg, WithFile(path), envMap(map[string]string{"CONFIG_DB_HOST": "db.env"}), flags)
+	require.NoError(t, err)
+
+	got := map[string]Setting{}
+	for _, s := range settings {
+		got[s.Path] = s
+	}
+	assert.Equal(t, Setting{
+		Path:    "port",
+		Env:     "CONFIG_PORT",
+		Flag:    "-port",
+		Type:    "int",
+		Default: "8080",
+		Desc:    "port to listen on",
+		Value:   9000,
+		Source:  SourceFile,
+	}, got["port"])
+	assert.Equal(t, SourceDefault, got["env"].Source)
+	assert.Equal(t, SourceDefault, got["name"].Source)
+	assert.Equal(t, SourceEnv, got["db.host"].Source)
+	assert.Equal(t, SourceFlag, got["debug"].Source)
+	assert.Equal(t, Source(""), got["db.replicas"].Source)
+	assert.Empty(t, got["db.replicas"].Value)
+	assert.Equal(t, "info", got["log.level"].Value)
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintSettings(&buf, settings))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(settings)+1)
+	assert.Equal(t, []string{"KEY", "FLAG", "ENV", "VALUE", "SOURCE", "DESCRIPTION"}, strings.Fields(lines[0]))
+	assert.Contains(t, buf.String(), "db.host")
+	assert.Equal(t, []string{"port", "-port", "CONFIG_PORT", "9000", "file", "port", "to", "listen", "on"}, strings.Fields(lines[4]))
+}
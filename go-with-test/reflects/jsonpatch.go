@@ -0,0 +1,337 @@
+package reflects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	InvalidPatchError      = errors.New("invalid patch")
+	PatchPathNotFoundError = errors.New("path not found")
+	PatchTestFailedError   = errors.New("test operation failed")
+)
+
+// PatchOperation RFC 6902 JSON Patch 中的一个操作
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch 把变更转换为 RFC 6902 JSON Patch 文档，应用到 a 的 JSON 上可以得到 b 的 JSON
+func (cs Changes) JSONPatch() ([]byte, error) {
+	ops := make([]PatchOperation, 0, len(cs))
+	for _, c := range cs {
+		op := PatchOperation{Path: c.Pointer}
+		switch {
+		case c.Type == Removed || c.toOmitted:
+			op.Op = "remove"
+		case c.Type == Added || c.fromOmitted:
+			op.Op = "add"
+		default:
+			op.Op = "replace"
+		}
+		if op.Op != "remove" {
+			value, err := json.Marshal(c.To)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Path, err)
+			}
+			op.Value = value
+		}
+		ops = append(ops, op)
+	}
+	return json.Marshal(ops)
+}
+
+// ApplyPatch 把 RFC 6902 JSON Patch 应用到 JSON 文档上，支持 add/remove/replace/move/copy/test。
+// 操作按顺序执行，任何一个失败都会返回错误，原文档不受影响。
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	var ops []PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidPatchError, err)
+	}
+	// 数字保留为 json.Number，补丁没有涉及的大整数原样输出
+	var root any
+	if err := decodeJSON(doc, &root); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if root, err = applyOperation(root, op); err != nil {
+			return nil, fmt.Errorf("patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+// Patch 把 JSON Patch 应用到 ptr 指向的值上：先编码为 JSON，打补丁后再解码回一个新值，成功后才替换 *ptr。
+// 不参与 JSON 编码的字段（未导出字段、json:"-"）会被重置为零值。
+func Patch(ptr any, patch []byte) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("Patch expects a non-nil pointer, got %T", ptr)
+	}
+	doc, err := json.Marshal(ptr)
+	if err != nil {
+		return err
+	}
+	patched, err := ApplyPatch(doc, patch)
+	if err != nil {
+		return err
+	}
+	out := reflect.New(v.Elem().Type())
+	if err := json.Unmarshal(patched, out.Interface()); err != nil {
+		return err
+	}
+	v.Elem().Set(out.Elem())
+	return nil
+}
+
+func applyOperation(root any, op PatchOperation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", InvalidPatchError)
+		}
+		var v any
+		err := decodeJSON(op.Value, &v)
+		return v, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return addAt(root, path, v)
+	case "remove":
+		return removeAt(root, path)
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := getAt(root, path); err != nil {
+			return nil, err
+		}
+		if root, err = removeAt(root, path); err != nil {
+			return nil, err
+		}
+		return addAt(root, path, v)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := getAt(root, from)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		if op.Op == "copy" {
+			// 深拷贝，避免同一个 map/切片 出现在两个位置
+			data, _ := json.Marshal(v)
+			_ = decodeJSON(data, &v)
+			return addAt(root, path, v)
+		}
+		if op.From == op.Path {
+			return root, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move %s into its own child", InvalidPatchError, op.From)
+		}
+		if root, err = removeAt(root, from); err != nil {
+			return nil, err
+		}
+		return addAt(root, path, v)
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := getAt(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(got, want) {
+			return nil, PatchTestFailedError
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", InvalidPatchError, op.Op)
+}
+
+// jsonEqual 按 RFC 6902 的 test 语义比较两个 JSON 值，数字按数值比较，例如 1 与 1.0 相等
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okA := new(big.Rat).SetString(a.String())
+		y, okB := new(big.Rat).SetString(b.String())
+		return okA && okB && x.Cmp(y) == 0
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// parsePointer 解析 RFC 6901 JSON Pointer，空串表示整个文档
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", InvalidPatchError, p)
+	}
+	segs := strings.Split(p[1:], "/")
+	for i, s := range segs {
+		segs[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+	}
+	return segs, nil
+}
+
+func getAt(doc any, path []string) (any, error) {
+	for _, seg := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			v, ok := d[seg]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, seg)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(seg, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, seg)
+		}
+	}
+	return doc, nil
+}
+
+// updateParent 找到 path 的父容器，用 fn 修改后写回，返回新的文档
+func updateParent(doc any, path []string, fn func(parent any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		child, ok := d[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, path[0])
+		}
+		child, err := updateParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[path[0]] = child
+		return d, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(d)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateParent(d[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = child
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, path[0])
+}
+
+func addAt(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, path, func(parent any, key string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[key] = value
+			return p, nil
+		case []any:
+			if key == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], append([]any{value}, p[i:]...)...), nil
+		}
+		return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, key)
+	})
+}
+
+func removeAt(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return updateParent(doc, path, func(parent any, key string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, key)
+			}
+			delete(p, key)
+			return p, nil
+		case []any:
+			i, err := arrayIndex(key, len(p)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %s", PatchPathNotFoundError, key)
+	})
+}
+
+// arrayIndex 解析数组下标，合法范围为 [0, max]
+func arrayIndex(seg string, max int) (int, error) {
+	i, err := strconv.Atoi(seg)
+	if err != nil || i < 0 || (len(seg) > 1 && seg[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", InvalidPatchError, seg)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: index %d out of range", PatchPathNotFoundError, i)
+	}
+	return i, nil
+}
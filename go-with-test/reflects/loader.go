@@ -0,0 +1,675 @@
+package reflects
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load 按 默认值(default tag) < 配置文件 < 环境变量 < 命令行参数 的优先级填充 ptr 指向的结构体。
+//
+// 字段的配置键取自 json tag（没有 tag 时使用字段名），嵌套结构体的环境变量名由各级键拼接而成，
+// 例如 `json:"db"` 下的 `json:"max-conns"` 对应 CONFIG_DB_MAX_CONNS。
+// 支持嵌套结构体、切片、map、指针、time.Duration 以及实现了 encoding.TextUnmarshaler 的类型。
+// 所有字段的错误会被汇总到 *LoadError 中一并返回，而不是遇到第一个错误就停止。
+// 加载成功后会按 validate tag 校验，校验失败返回 *ValidationError。
+func Load(ptr any, opts ...Option) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load expects a non-nil pointer to struct, got %T", ptr)
+	}
+
+	l := &loader{envPrefix: "CONFIG", lookupEnv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l.load(v.Elem())
+}
+
+// Option Load 的可选项
+type Option func(*loader)
+
+// WithFile 从配置文件加载，格式由扩展名决定：.json / .yaml / .yml / .toml，文件不存在时报错
+func WithFile(path string) Option {
+	return func(l *loader) {
+		l.file, l.fileOptional = path, false
+	}
+}
+
+// WithOptionalFile 同 WithFile，但文件不存在时直接跳过
+func WithOptionalFile(path string) Option {
+	return func(l *loader) {
+		l.file, l.fileOptional = path, true
+	}
+}
+
+// WithEnvPrefix 修改环境变量前缀，默认为 CONFIG；传空串表示不加前缀
+func WithEnvPrefix(prefix string) Option {
+	return func(l *loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithLookupEnv 替换环境变量的查找函数，便于测试
+func WithLookupEnv(lookup func(key string) (string, bool)) Option {
+	return func(l *loader) {
+		l.lookupEnv = lookup
+	}
+}
+
+// Source 配置值的来源
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// FieldError 单个字段的加载错误，Path 为配置键路径，例如 db.hosts[1]
+type FieldError struct {
+	Path   string
+	Source Source
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (from %s): %v", e.Path, e.Source, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError 汇总一次加载中所有字段的错误
+type LoadError struct {
+	Errors []*FieldError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "config: " + strings.Join(msgs, "; ")
+}
+
+func (e *LoadError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+type loader struct {
+	file         string
+	fileOptional bool
+	envPrefix    string
+	lookupEnv    func(key string) (string, bool)
+	keyFile      string
+	key          []byte
+	// flags 命令行中显式设置过的参数，键为配置路径，见 RegisterFlags
+	flags map[string]string
+
+	source Source
+	errs   []*FieldError
+	// sources 非 nil 时记录每个字段最终值的来源，键为配置路径
+	sources map[string]Source
+}
+
+func (l *loader) load(v reflect.Value) error {
+	if l.keyFile != "" {
+		key, err := ReadKeyFile(l.keyFile)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		l.key = key
+	}
+
+	l.source = SourceDefault
+	l.applyTagged(v, "", l.envPrefix, func(sf reflect.StructField, _, _ string) (string, bool) {
+		return sf.Tag.Lookup("default")
+	})
+
+	if l.file != "" {
+		l.source = SourceFile
+		if err := l.loadFile(v); err != nil {
+			return err
+		}
+	}
+
+	l.source = SourceEnv
+	l.applyTagged(v, "", l.envPrefix, func(_ reflect.StructField, path, env string) (string, bool) {
+		s, ok, err := l.lookupEnvFile(env)
+		if err != nil {
+			l.fail(path, err)
+		}
+		return s, ok
+	})
+
+	if len(l.flags) > 0 {
+		l.source = SourceFlag
+		l.applyTagged(v, "", l.envPrefix, func(_ reflect.StructField, path, _ string) (string, bool) {
+			s, ok := l.flags[path]
+			return s, ok
+		})
+	}
+
+	if len(l.errs) > 0 {
+		return &LoadError{Errors: l.errs}
+	}
+
+	var invalid []*ValidationFieldError
+	validateStruct(v, "", l.envPrefix, &invalid)
+	if len(invalid) > 0 {
+		return &ValidationError{Errors: invalid}
+	}
+	return nil
+}
+
+func (l *loader) loadFile(v reflect.Value) error {
+	data, err := os.ReadFile(l.file)
+	if err != nil {
+		if l.fileOptional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("config: read %s: %w", l.file, err)
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(l.file)); ext {
+	case ".json":
+		// 数字保留为 json.Number，由 setRaw 按字段类型转换，超过 2^53 的整数不会经过 float64 丢失精度
+		err = decodeJSON(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config: unsupported file format %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", l.file, err)
+	}
+
+	l.applyMap(v, raw, "")
+	return nil
+}
+
+func (l *loader) fail(path string, err error) {
+	l.errs = append(l.errs, &FieldError{Path: path, Source: l.source, Err: err})
+}
+
+func (l *loader) mark(path string) {
+	if l.sources != nil {
+		l.sources[path] = l.source
+	}
+}
+
+// applyTagged 遍历结构体字段，对每个叶子字段调用 lookup 获取字符串值并写入，返回是否写入过任何字段。
+// 指针类型的嵌套结构体只有在其内部确实有值时才会被分配。
+func (l *loader) applyTagged(v reflect.Value, path, env string, lookup func(sf reflect.StructField, path, env string) (string, bool)) bool {
+	set := false
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		fpath, fenv := joinPath(path, key), joinEnv(env, key)
+		if sf.Anonymous && !hasJSONName(sf) {
+			// 嵌入结构体的字段提升到外层，与 encoding/json 的行为一致
+			fpath, fenv = path, env
+		}
+
+		if isNestedStruct(sf.Type) {
+			if sf.Type.Kind() == reflect.Pointer {
+				if !fv.IsNil() {
+					set = l.applyTagged(fv.Elem(), fpath, fenv, lookup) || set
+					continue
+				}
+				tmp := reflect.New(sf.Type.Elem())
+				if l.applyTagged(tmp.Elem(), fpath, fenv, lookup) {
+					fv.Set(tmp)
+					set = true
+				}
+				continue
+			}
+			set = l.applyTagged(fv, fpath, fenv, lookup) || set
+			continue
+		}
+
+		s, ok := lookup(sf, fpath, fenv)
+		if !ok {
+			continue
+		}
+		if isSecret(sf) {
+			var err error
+			if s, err = decryptSecret(l.key, s); err != nil {
+				l.fail(fpath, redactError(err))
+				continue
+			}
+		}
+		if err := setString(fv, s); err != nil {
+			if isSecret(sf) {
+				err = redactError(err)
+			}
+			l.fail(fpath, err)
+			continue
+		}
+		l.mark(fpath)
+		set = true
+	}
+	return set
+}
+
+// applyMap 将配置文件解析出的 map 写入结构体
+func (l *loader) applyMap(v reflect.Value, raw map[string]any, path string) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && !hasJSONName(sf) && isNestedStruct(sf.Type) {
+			l.setRaw(fv, raw, path)
+			continue
+		}
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		if s, isString := value.(string); isString && isSecret(sf) {
+			plain, err := decryptSecret(l.key, s)
+			if err != nil {
+				l.fail(joinPath(path, key), redactError(err))
+				continue
+			}
+			value = plain
+		}
+		start := len(l.errs)
+		l.setRaw(fv, value, joinPath(path, key))
+		if isSecret(sf) {
+			for _, fe := range l.errs[start:] {
+				fe.Err = redactError(fe.Err)
+			}
+		}
+		if !isNestedStruct(sf.Type) {
+			l.mark(joinPath(path, key))
+		}
+	}
+}
+
+// setRaw 将 JSON/YAML/TOML 解码出的通用值写入 v
+func (l *loader) setRaw(v reflect.Value, raw any, path string) {
+	if raw == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		l.setRaw(v.Elem(), raw, path)
+		return
+	}
+
+	if n, ok := raw.(json.Number); ok {
+		raw = numberFor(n, v.Type())
+	} else if v.Kind() == reflect.Interface {
+		raw = plainNumbers(raw)
+	}
+
+	rv := reflect.ValueOf(raw)
+	if v.Type() == durationType && (rv.CanInt() || rv.CanFloat()) {
+		// 数字形式的时长按纳秒处理，与 json.Marshal(time.Duration) 的输出一致
+		if rv.CanInt() {
+			v.SetInt(rv.Int())
+		} else {
+			v.SetInt(int64(rv.Float()))
+		}
+		return
+	}
+	if rv.Type().AssignableTo(v.Type()) && v.Kind() != reflect.Map && v.Kind() != reflect.Slice {
+		// 例如 TOML 解析出的 time.Time，或 any 类型的字段
+		v.Set(rv)
+		return
+	}
+	if isScalar(rv.Kind()) {
+		if err := setString(v, scalarString(raw)); err != nil {
+			l.fail(path, err)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m, ok := toStringMap(rv)
+		if !ok {
+			l.fail(path, fmt.Errorf("cannot use %T as %s", raw, v.Type()))
+			return
+		}
+		l.applyMap(v, m, path)
+	case reflect.Slice:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			l.fail(path, fmt.Errorf("cannot use %T as %s", raw, v.Type()))
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			l.setRaw(s.Index(i), rv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(s)
+	case reflect.Array:
+		if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Len() > v.Len() {
+			l.fail(path, fmt.Errorf("cannot use %T as %s", raw, v.Type()))
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			l.setRaw(v.Index(i), rv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		m, ok := toStringMap(rv)
+		if !ok {
+			l.fail(path, fmt.Errorf("cannot use %T as %s", raw, v.Type()))
+			return
+		}
+		out := reflect.MakeMapWithSize(v.Type(), len(m))
+		for k, item := range m {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setString(key, k); err != nil {
+				l.fail(fmt.Sprintf("%s[%s]", path, k), err)
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			l.setRaw(elem, item, fmt.Sprintf("%s[%s]", path, k))
+			out.SetMapIndex(key, elem)
+		}
+		v.Set(out)
+	default:
+		l.fail(path, fmt.Errorf("cannot use %T as %s", raw, v.Type()))
+	}
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setString 将字符串（环境变量、默认值）转换为字段类型后写入。
+// 切片写作 "a,b,c"，map 写作 "k1=v1,k2=v2"，也可以直接使用 JSON 字面量。
+func setString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setString(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(s))
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return setComposite(v, s)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// decodeJSON 与 json.Unmarshal 相同，但数字保留为 json.Number，超过 2^53 的整数不会经过 float64 丢失精度
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level JSON value")
+	}
+	return nil
+}
+
+func setComposite(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		var raw any
+		if err := decodeJSON([]byte(s), &raw); err != nil {
+			return err
+		}
+		l := &loader{}
+		l.setRaw(v, raw, "")
+		if len(l.errs) > 0 {
+			return l.errs[0].Err
+		}
+		return nil
+	}
+
+	var items []string
+	if s != "" {
+		items = strings.Split(s, ",")
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		out := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setString(out.Index(i), strings.TrimSpace(item)); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(out)
+	case reflect.Array:
+		if len(items) > v.Len() {
+			return fmt.Errorf("too many items for %s", v.Type())
+		}
+		for i, item := range items {
+			if err := setString(v.Index(i), strings.TrimSpace(item)); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+	case reflect.Map:
+		out := reflect.MakeMapWithSize(v.Type(), len(items))
+		for _, item := range items {
+			k, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid map item %q, want key=value", item)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setString(key, strings.TrimSpace(k)); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setString(elem, strings.TrimSpace(val)); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			out.SetMapIndex(key, elem)
+		}
+		v.Set(out)
+	default:
+		return fmt.Errorf("unsupported value %q for %s", s, v.Type())
+	}
+	return nil
+}
+
+// fieldKey 返回字段的配置键，未导出或 json:"-" 的字段返回 false
+func fieldKey(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+		return "", false
+	}
+	tag := sf.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = sf.Name
+	}
+	return name, true
+}
+
+func hasJSONName(sf reflect.StructField) bool {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	return name != ""
+}
+
+// isNestedStruct 判断是否为需要逐字段展开的结构体（自行实现文本解析的类型除外，例如 time.Time）
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// joinEnv 拼接环境变量名：大写，'-' 和 '.' 替换为 '_'
+func joinEnv(env, key string) string {
+	key = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+	if env == "" {
+		return key
+	}
+	return env + "_" + key
+}
+
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func scalarString(raw any) string {
+	switch x := raw.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// numberFor 按目标类型转换 json.Number：整数和字符串字段使用原始文本，由 setString 精确解析；
+// 时长和 any 字段在能表示为 int64 时使用 int64，否则使用 float64
+func numberFor(n json.Number, t reflect.Type) any {
+	switch {
+	case t == durationType || t.Kind() == reflect.Interface:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return n.String()
+}
+
+// plainNumbers 把写入 any 字段的值中嵌套的 json.Number 转换为 int64 或 float64
+func plainNumbers(raw any) any {
+	switch x := raw.(type) {
+	case json.Number:
+		return numberFor(x, reflect.TypeOf((*any)(nil)).Elem())
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, item := range x {
+			out[k] = plainNumbers(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, item := range x {
+			out[i] = plainNumbers(item)
+		}
+		return out
+	}
+	return raw
+}
+
+func toStringMap(rv reflect.Value) (map[string]any, bool) {
+	if rv.Kind() != reflect.Map {
+		return nil, false
+	}
+	out := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		out[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+	}
+	return out, true
+}
@@ -0,0 +1,258 @@
+package reflects
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DBConfig struct {
+	Host     string        `json:"host" default:"localhost"`
+	Port     int           `json:"port" default:"5432"`
+	Timeout  time.Duration `json:"timeout" default:"5s"`
+	Replicas []string      `json:"replicas"`
+}
+
+type LogConfig struct {
+	Level string `json:"level" default:"info"`
+}
+
+type Common struct {
+	Env string `json:"env" default:"dev"`
+}
+
+type AppConfig struct {
+	Common
+	Name    string            `json:"name" default:"app"`
+	Debug   bool              `json:"debug"`
+	Ratio   float64           `json:"ratio" default:"0.5"`
+	DB      DBConfig          `json:"db"`
+	Log     *LogConfig        `json:"log"`
+	Cache   *LogConfig        `json:"cache"`
+	Labels  map[string]string `json:"labels"`
+	Weights map[string]int    `json:"weights"`
+	Ports   []int             `json:"ports"`
+	Bind    net.IP            `json:"bind"`
+	Retry   *int              `json:"retry"`
+	secret  string
+	Ignored string `json:"-"`
+}
+
+func envMap(m map[string]string) Option {
+	return WithLookupEnv(func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	var cfg AppConfig
+	require.NoError(t, Load(&cfg, envMap(nil)))
+
+	assert.Equal(t, "dev", cfg.Env)
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, 0.5, cfg.Ratio)
+	assert.Equal(t, "localhost", cfg.DB.Host)
+	assert.Equal(t, 5432, cfg.DB.Port)
+	assert.Equal(t, 5*time.Second, cfg.DB.Timeout)
+	// 指针结构体内有默认值时才会被分配
+	require.NotNil(t, cfg.Log)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.Nil(t, cfg.Retry)
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{
+			"name": "from-json",
+			"db": {"host": "db.local", "timeout": "1m", "replicas": ["r1", "r2"]},
+			"labels": {"team": "core"},
+			"ports": [80, 443],
+			"bind": "10.0.0.1",
+			"retry": 3
+		}`,
+		"config.yaml": `
+name: from-yaml
+db:
+  host: db.local
+  timeout: 1m
+  replicas: [r1, r2]
+labels:
+  team: core
+ports: [80, 443]
+bind: 10.0.0.1
+retry: 3
+`,
+		"config.toml": `
+name = "from-toml"
+ports = [80, 443]
+bind = "10.0.0.1"
+retry = 3
+
+[db]
+host = "db.local"
+timeout = "1m"
+replicas = ["r1", "r2"]
+
+[labels]
+team = "core"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			var cfg AppConfig
+			require.NoError(t, Load(&cfg, WithFile(writeFile(t, name, content)), envMap(nil)))
+
+			assert.Equal(t, "from-"+filepath.Ext(name)[1:], cfg.Name)
+			assert.Equal(t, "db.local", cfg.DB.Host)
+			assert.Equal(t, 5432, cfg.DB.Port, "default kept when file omits the key")
+			assert.Equal(t, time.Minute, cfg.DB.Timeout)
+			assert.Equal(t, []string{"r1", "r2"}, cfg.DB.Replicas)
+			assert.Equal(t, map[string]string{"team": "core"}, cfg.Labels)
+			assert.Equal(t, []int{80, 443}, cfg.Ports)
+			assert.Equal(t, "10.0.0.1", cfg.Bind.String())
+			require.NotNil(t, cfg.Retry)
+			assert.Equal(t, 3, *cfg.Retry)
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{"name": "from-file", "debug": false, "db": {"port": 6000}}`)
+
+	var cfg AppConfig
+	err := Load(&cfg, WithFile(path), envMap(map[string]string{
+		"CONFIG_NAME":         "from-env",
+		"CONFIG_DEBUG":        "true",
+		"CONFIG_ENV":          "prod",
+		"CONFIG_DB_REPLICAS":  "a, b",
+		"CONFIG_WEIGHTS":      "x=1,y=2",
+		"CONFIG_PORTS":        "[8080, 8443]",
+		"CONFIG_CACHE_LEVEL":  "debug",
+		"CONFIG_BIND":         "::1",
+		"CONFIG_DB_TIMEOUT":   "250ms",
+		"CONFIG_IGNORED":      "nope",
+		"CONFIG_SECRET":       "nope",
+		"CONFIG_UNRELATED_DB": "nope",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-env", cfg.Name)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, "prod", cfg.Env)
+	assert.Equal(t, 6000, cfg.DB.Port)
+	assert.Equal(t, []string{"a", "b"}, cfg.DB.Replicas)
+	assert.Equal(t, map[string]int{"x": 1, "y": 2}, cfg.Weights)
+	assert.Equal(t, []int{8080, 8443}, cfg.Ports)
+	require.NotNil(t, cfg.Cache)
+	assert.Equal(t, "debug", cfg.Cache.Level)
+	assert.Equal(t, "::1", cfg.Bind.String())
+	assert.Equal(t, 250*time.Millisecond, cfg.DB.Timeout)
+	assert.Empty(t, cfg.Ignored)
+	assert.Empty(t, cfg.secret)
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	path := writeFile(t, "config.json", `{"db": {"port": "not-a-number", "replicas": {"a": 1}}, "ports": [1, "x"]}`)
+
+	var cfg AppConfig
+	err := Load(&cfg, WithFile(path), envMap(map[string]string{
+		"CONFIG_DEBUG":      "maybe",
+		"CONFIG_DB_TIMEOUT": "soon",
+	}))
+
+	var loadErr *LoadError
+	require.True(t, errors.As(err, &loadErr), "got %v", err)
+
+	got := map[string]Source{}
+	for _, fe := range loadErr.Errors {
+		got[fe.Path] = fe.Source
+	}
+	assert.Equal(t, map[string]Source{
+		"db.port":     SourceFile,
+		"db.replicas": SourceFile,
+		"ports[1]":    SourceFile,
+		"debug":       SourceEnv,
+		"db.timeout":  SourceEnv,
+	}, got)
+	assert.Contains(t, err.Error(), "db.port (from file)")
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	var cfg AppConfig
+
+	assert.NoError(t, Load(&cfg, WithOptionalFile(filepath.Join(t.TempDir(), "missing.json")), envMap(nil)))
+	assert.Error(t, Load(&cfg, WithFile(filepath.Join(t.TempDir(), "missing.json")), envMap(nil)))
+	assert.Error(t, Load(&cfg, WithFile(writeFile(t, "config.ini", "a=b")), envMap(nil)))
+	assert.Error(t, Load(&cfg, WithFile(writeFile(t, "config.json", "{")), envMap(nil)))
+	assert.Error(t, Load(cfg))
+	assert.Error(t, Load((*AppConfig)(nil)))
+}
+
+func TestLoadEnvPrefix(t *testing.T) {
+	var cfg AppConfig
+	require.NoError(t, Load(&cfg, WithEnvPrefix("APP"), envMap(map[string]string{"APP_NAME": "prefixed"})))
+	assert.Equal(t, "prefixed", cfg.Name)
+}
+
+func TestLoadLargeIntegers(t *testing.T) {
+	type bigConfig struct {
+		ID      int64          `json:"id"`
+		Counter uint64         `json:"counter"`
+		Ratio   float64        `json:"ratio"`
+		Timeout time.Duration  `json:"timeout"`
+		Extra   map[string]any `json:"extra"`
+		Raw     any            `json:"raw"`
+	}
+	path := writeFile(t, "config.json", `{
+		"id": 9007199254740993,
+		"counter": 18446744073709551615,
+		"ratio": 0.25,
+		"timeout": 1500000000,
+		"extra": {"big": 9007199254740993, "half": 0.5},
+		"raw": [9007199254740993]
+	}`)
+
+	var cfg bigConfig
+	require.NoError(t, Load(&cfg, WithFile(path), envMap(nil)))
+	assert.Equal(t, int64(9007199254740993), cfg.ID)
+	assert.Equal(t, uint64(18446744073709551615), cfg.Counter)
+	assert.Equal(t, 0.25, cfg.Ratio)
+	assert.Equal(t, 1500*time.Millisecond, cfg.Timeout)
+	assert.Equal(t, map[string]any{"big": int64(9007199254740993), "half": 0.5}, cfg.Extra)
+	assert.Equal(t, []any{int64(9007199254740993)}, cfg.Raw)
+
+	t.Run("json literals from env", func(t *testing.T) {
+		type envConfig struct {
+			Extra map[string]any `json:"extra"`
+			List  []any          `json:"list"`
+			IDs   []int64        `json:"ids"`
+		}
+		var cfg envConfig
+		require.NoError(t, Load(&cfg, envMap(map[string]string{
+			"CONFIG_EXTRA": `{"big": 9007199254740993}`,
+			"CONFIG_LIST":  `[9007199254740993, 0.5]`,
+			"CONFIG_IDS":   `[9007199254740993]`,
+		})))
+		assert.Equal(t, map[string]any{"big": int64(9007199254740993)}, cfg.Extra)
+		assert.Equal(t, []any{int64(9007199254740993), 0.5}, cfg.List)
+		assert.Equal(t, []int64{9007199254740993}, cfg.IDs)
+
+		err := Load(&cfg, envMap(map[string]string{"CONFIG_LIST": `[1] [2]`}))
+		assert.Error(t, err)
+	})
+}
@@ -1,20 +1,21 @@
 package reflects
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Config struct {
-	Name  string `json:"server-name"`
-	Ip    string `json:"server-ip"`
-	Port  int    `json:"server-port"`
-	Debug bool   `json:"debug"`
+	Name  string `json:"server-name" validate:"required" desc:"server name"`
+	Ip    string `json:"server-ip" validate:"required,ip" desc:"address to listen on"`
+	Port  int    `json:"server-port" validate:"min=1,max=65535" desc:"port to listen on"`
+	Debug bool   `json:"debug" desc:"enable debug logging"`
 }
 
 // 简陋版
@@ -41,62 +42,52 @@ func readConfigV1() *Config {
 var (
 	config     *Config
 	configOnce sync.Once
+	// configWatcher 在 WatchConfig 启动的 Watcher 运行期间不为 nil，GetConfig 改为返回热加载的最新配置
+	configWatcher atomic.Pointer[Watcher[Config]]
 )
 
 func GetConfig() *Config {
+	if w := configWatcher.Load(); w != nil {
+		return w.Current()
+	}
 	configOnce.Do(loadConfig)
 	return config
 }
 
+// WatchConfig 开启 settings.json 的热加载（文件变更或 SIGHUP），直到 ctx 结束。
+// 重复调用返回同一个 Watcher；ctx 结束后 Watcher 被清除，再次调用会创建新的 Watcher。
+func WatchConfig(ctx context.Context) (*Watcher[Config], error) {
+	if w := configWatcher.Load(); w != nil {
+		return w, nil
+	}
+	w, err := NewWatcher[Config]("settings.json", WithErrorHandler(func(err error) {
+		log.Printf("reload config failed: %v", err)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if !configWatcher.CompareAndSwap(nil, w) {
+		return configWatcher.Load(), nil
+	}
+	go func() {
+		_ = w.Run(ctx)
+		configWatcher.CompareAndSwap(w, nil)
+	}()
+	return w, nil
+}
+
 // 可以将readConfig直接改为loadConfig
 func loadConfig() {
 	_ = readConfig()
 }
 
+// readConfig 基于通用的 Load 实现：默认值 < settings.json < CONFIG_* 环境变量
 func readConfig() *Config {
 	fmt.Println("READ CONFIG!!!")
 	//config := Config{}
 	config = &Config{}
-	// 读配置文件
-	if data, err := os.ReadFile("settings.json"); err == nil {
-		_ = json.Unmarshal(data, config)
-	}
-
-	//反射类型
-	typ := reflect.TypeOf(*config)
-	// 反射值，兼容指针
-	value := reflect.Indirect(reflect.ValueOf(config))
-	// 遍历所有字段
-	for i := 0; i < typ.NumField(); i++ {
-		f := typ.Field(i)
-		// json tag 解析
-		if v, ok := f.Tag.Lookup("json"); ok {
-			// 环境变量名映射
-			key := fmt.Sprintf("CONFIG_%s", strings.ReplaceAll(v, "-", "_"))
-			// 环境变量查找
-			if env, exist := os.LookupEnv(key); exist {
-				field := value.FieldByName(f.Name)
-				// 可写判定
-				if field.IsValid() && field.CanSet() {
-					// 类型判定
-					switch field.Kind() {
-					case reflect.String:
-						field.SetString(env)
-					case reflect.Int:
-						// 类型转换
-						if intValue, err := strconv.Atoi(env); err == nil {
-							field.SetInt(int64(intValue))
-						}
-					case reflect.Bool:
-						if boolValue, err := strconv.ParseBool(env); err == nil {
-							field.SetBool(boolValue)
-						}
-					default:
-						fmt.Printf("Unsupported field type: %s\n", field.Kind())
-					}
-				}
-			}
-		}
+	if err := Load(config, WithOptionalFile("settings.json")); err != nil {
+		fmt.Printf("load config failed: %v\n", err)
 	}
 
 	return config
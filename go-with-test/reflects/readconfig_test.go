@@ -1,11 +1,14 @@
 package reflects
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestReadConfig(t *testing.T) {
@@ -29,6 +32,42 @@ func TestReadConfig(t *testing.T) {
 	assert.Equal(t, cfg1, cfg2)
 }
 
+// ctx 结束后全局 Watcher 被清除，再次调用 WatchConfig 可以重新开启热加载
+func TestWatchConfigRestart(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	writeConfig(t, "settings.json", `{"server-name": "v1", "server-ip": "127.0.0.1", "server-port": 8081}`)
+
+	waitStopped := func() {
+		t.Helper()
+		require.Eventually(t, func() bool { return configWatcher.Load() == nil }, 5*time.Second, 10*time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first, err := WatchConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", GetConfig().Name)
+	cancel()
+	waitStopped()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		waitStopped()
+	})
+	second, err := WatchConfig(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+
+	// 监听启动前的写入可能被错过，因此持续写入直到生效
+	require.Eventually(t, func() bool {
+		writeConfig(t, "settings.json", `{"server-name": "v2", "server-ip": "127.0.0.1", "server-port": 8081}`)
+		return GetConfig().Name == "v2"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
 // go test -bench .
 // BenchmarkNew-16                 60195547                20.58 ns/op
 // BenchmarkReflectNew-16          42175406                26.96 ns/op
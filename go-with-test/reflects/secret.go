@@ -0,0 +1,445 @@
+package reflects
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redactedText 敏感字段在各种输出中的替代文本
+const redactedText = "******"
+
+// encryptedPrefix 加密值的前缀，格式为 enc:<base64(nonce+密文)>，见 EncryptSecret
+const encryptedPrefix = "enc:"
+
+var (
+	NoKeyFileError         = errors.New("encrypted value requires a key file, see WithKeyFile")
+	InvalidKeyError        = errors.New("key must be 32 bytes encoded in base64")
+	InvalidCiphertextError = errors.New("invalid encrypted value")
+)
+
+// WithKeyFile 指定解密用的密钥文件，文件内容为 base64 编码的 32 字节 AES-256 密钥，可用 GenerateKeyFile 生成。
+// 设置后，secret 字段中以 enc: 开头的值在写入前会被解密，无论它来自默认值、配置文件、环境变量还是命令行。
+func WithKeyFile(path string) Option {
+	return func(l *loader) {
+		l.keyFile = path
+	}
+}
+
+// GenerateKeyFile 生成随机密钥并写入 path，文件权限为 0600
+func GenerateKeyFile(path string) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+}
+
+// ReadKeyFile 读取 GenerateKeyFile 生成的密钥
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s: %w", path, InvalidKeyError)
+	}
+	return key, nil
+}
+
+// EncryptSecret 使用 AES-256-GCM 加密 plaintext，返回可以直接写进配置文件或环境变量的 enc:... 字符串
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret 解密 EncryptSecret 的结果，不带 enc: 前缀的值原样返回
+func decryptSecret(key []byte, s string) (string, error) {
+	encoded, ok := strings.CutPrefix(s, encryptedPrefix)
+	if !ok {
+		return s, nil
+	}
+	if key == nil {
+		return "", NoKeyFileError
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", InvalidCiphertextError
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", InvalidCiphertextError
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, InvalidKeyError
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isSecret 判断字段是否标记了 `secret:"true"`
+func isSecret(sf reflect.StructField) bool {
+	secret, _ := strconv.ParseBool(sf.Tag.Get("secret"))
+	return secret
+}
+
+// redactError 去掉 secret 字段解析错误中的原始输入，只保留不含输入的原因，例如 strconv.ErrSyntax
+func redactError(err error) error {
+	var numErr *strconv.NumError
+	switch {
+	case errors.As(err, &numErr):
+		return fmt.Errorf("invalid value %s: %w", redactedText, numErr.Err)
+	case errors.Is(err, NoKeyFileError), errors.Is(err, InvalidKeyError), errors.Is(err, InvalidCiphertextError):
+		return err
+	}
+	return fmt.Errorf("invalid value %s", redactedText)
+}
+
+// lookupEnvFile 支持 Docker/Kubernetes secrets 的约定：env 未设置时读取 env_FILE 指向的文件，去掉末尾换行。
+// 两者同时设置时无法判断用户的意图，直接报错。
+func (l *loader) lookupEnvFile(env string) (string, bool, error) {
+	s, ok := l.lookupEnv(env)
+	path, fileOK := l.lookupEnv(env + "_FILE")
+	if !fileOK {
+		return s, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", env, env)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", env, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// Redacted 配置的脱敏视图，String/GoString/MarshalJSON 都会把 secret 字段替换为 ******，
+// 可以放心地交给日志：
+//
+//	log.Printf("config: %v", reflects.Redact(&cfg))
+type Redacted struct {
+	v reflect.Value
+}
+
+// Redact 返回 v 的脱敏视图，v 为结构体或结构体指针
+func Redact(v any) Redacted {
+	return Redacted{v: reflect.ValueOf(v)}
+}
+
+// String 输出形如 {name:app db:{host:localhost password:******}}，键与配置文件一致
+func (r Redacted) String() string {
+	var buf bytes.Buffer
+	writeRedacted(&buf, r.v, false)
+	return buf.String()
+}
+
+// GoString 让 %#v 同样不泄露敏感字段
+func (r Redacted) GoString() string {
+	if !r.v.IsValid() {
+		return "<nil>"
+	}
+	t := r.v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.String() + r.String()
+}
+
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeRedacted(&buf, r.v, true); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// redactedValue 返回可以安全输出的值，可能包含 secret 字段的值包装为 Redacted
+func redactedValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if hasSecret(v) {
+		return Redacted{v: v}
+	}
+	return v.Interface()
+}
+
+// hasSecret 判断值中是否实际含有 secret 字段，接口、切片和 map 按其中的元素判断
+func hasSecret(v reflect.Value) bool {
+	if !v.IsValid() || !containsSecret(v.Type(), map[reflect.Type]bool{}) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && hasSecret(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasSecret(v.Index(i)) {
+				return true
+			}
+		}
+		return false
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasSecret(iter.Value()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// containsSecret 判断 t 的值中是否可能出现 secret 字段，接口的动态类型无法静态判断，一律视为可能
+func containsSecret(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return containsSecret(t.Elem(), seen)
+	case reflect.Struct:
+		if !isNestedStruct(t) {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if _, ok := fieldKey(sf); !ok {
+				continue
+			}
+			if isSecret(sf) || containsSecret(sf.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeRedacted 按配置键输出结构体，嵌入结构体的字段提升到外层；
+// 切片、数组、map 和接口逐个元素脱敏，其它类型的值整体输出
+func writeRedacted(buf *bytes.Buffer, v reflect.Value, asJSON bool) error {
+	for v.IsValid() && v.Kind() == reflect.Pointer && !v.IsNil() && isNestedStruct(v.Type()) {
+		v = v.Elem()
+	}
+	if v.IsValid() && v.CanInterface() && containsSecret(v.Type(), map[reflect.Type]bool{}) {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface:
+			if !v.IsNil() {
+				return writeRedacted(buf, v.Elem(), asJSON)
+			}
+		case reflect.Slice, reflect.Array:
+			if v.Kind() == reflect.Array || !v.IsNil() || !asJSON {
+				return writeRedactedList(buf, v, asJSON)
+			}
+		case reflect.Map:
+			if !v.IsNil() || !asJSON {
+				return writeRedactedMap(buf, v, asJSON)
+			}
+		}
+	}
+	if !v.IsValid() || v.Kind() != reflect.Struct || !isNestedStruct(v.Type()) {
+		return writeRedactedValue(buf, v, asJSON)
+	}
+
+	buf.WriteByte('{')
+	first := true
+	var walk func(v reflect.Value) error
+	walk = func(v reflect.Value) error {
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			key, ok := fieldKey(sf)
+			if !ok {
+				continue
+			}
+			fv := v.Field(i)
+			if sf.Anonymous && !hasJSONName(sf) && isNestedStruct(sf.Type) {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				if err := walk(fv); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !first {
+				if asJSON {
+					buf.WriteByte(',')
+				} else {
+					buf.WriteByte(' ')
+				}
+			}
+			first = false
+			if asJSON {
+				k, _ := json.Marshal(key)
+				buf.Write(k)
+			} else {
+				buf.WriteString(key)
+			}
+			buf.WriteByte(':')
+
+			if isSecret(sf) && !fv.IsZero() {
+				if asJSON {
+					buf.WriteString(strconv.Quote(redactedText))
+				} else {
+					buf.WriteString(redactedText)
+				}
+				continue
+			}
+			if err := writeRedacted(buf, fv, asJSON); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(v); err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeRedactedValue(buf *bytes.Buffer, v reflect.Value, asJSON bool) error {
+	var x any
+	if v.IsValid() && v.CanInterface() {
+		x = v.Interface()
+	}
+	if !asJSON {
+		fmt.Fprint(buf, x)
+		return nil
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+// writeRedactedList 输出形如 [a b] 的切片或数组，JSON 模式下为 [a,b]
+func writeRedactedList(buf *bytes.Buffer, v reflect.Value, asJSON bool) error {
+	buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			if asJSON {
+				buf.WriteByte(',')
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		if err := writeRedacted(buf, v.Index(i), asJSON); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// writeRedactedMap 与 fmt、encoding/json 一样按键排序输出 map，保证输出稳定
+func writeRedactedMap(buf *bytes.Buffer, v reflect.Value, asJSON bool) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		if asJSON {
+			var err error
+			if key, err = jsonMapKey(iter.Key()); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	if asJSON {
+		buf.WriteByte('{')
+	} else {
+		buf.WriteString("map[")
+	}
+	for i, e := range entries {
+		if i > 0 {
+			if asJSON {
+				buf.WriteByte(',')
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		if asJSON {
+			k, _ := json.Marshal(e.key)
+			buf.Write(k)
+		} else {
+			buf.WriteString(e.key)
+		}
+		buf.WriteByte(':')
+		if err := writeRedacted(buf, e.value, asJSON); err != nil {
+			return err
+		}
+	}
+	if asJSON {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return nil
+}
+
+// jsonMapKey 按 encoding/json 的规则把 map 的键转换为字符串
+func jsonMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: k.Type()}
+}
@@ -0,0 +1,243 @@
+package reflects
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SecretDB struct {
+	User     string `json:"user" default:"root"`
+	Password string `json:"password" secret:"true"`
+}
+
+type secretConfig struct {
+	Common
+	Name  string    `json:"name" default:"app"`
+	Token string    `json:"token" secret:"true"`
+	DB    SecretDB  `json:"db"`
+	Cache *SecretDB `json:"cache"`
+}
+
+func TestRedact(t *testing.T) {
+	cfg := secretConfig{
+		Common: Common{Env: "prod"},
+		Name:   "app",
+		Token:  "t0ken",
+		DB:     SecretDB{User: "root", Password: "hunter2"},
+	}
+
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		out := fmt.Sprintf(format, Redact(&cfg))
+		assert.NotContains(t, out, "hunter2", format)
+		assert.NotContains(t, out, "t0ken", format)
+	}
+	assert.Equal(t, "{env:prod name:app token:****** db:{user:root password:******} cache:<nil>}", Redact(&cfg).String())
+	assert.Equal(t, "reflects.secretConfig{env:prod name:app token:****** db:{user:root password:******} cache:<nil>}", fmt.Sprintf("%#v", Redact(cfg)))
+
+	data, err := json.Marshal(Redact(&cfg))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"env": "prod",
+		"name": "app",
+		"token": "******",
+		"db": {"user": "root", "password": "******"},
+		"cache": null
+	}`, string(data))
+
+	t.Run("empty secrets are shown as empty", func(t *testing.T) {
+		assert.Contains(t, Redact(secretConfig{}).String(), "token: ")
+	})
+}
+
+func TestSecretEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_password")
+	require.NoError(t, os.WriteFile(secretFile, []byte("hunter2\n"), 0600))
+
+	var cfg secretConfig
+	require.NoError(t, Load(&cfg, envMap(map[string]string{
+		"CONFIG_DB_PASSWORD_FILE": secretFile,
+		"CONFIG_TOKEN":            "t0ken",
+	})))
+	assert.Equal(t, "hunter2", cfg.DB.Password)
+	assert.Equal(t, "t0ken", cfg.Token)
+
+	t.Run("both set", func(t *testing.T) {
+		err := Load(&secretConfig{}, envMap(map[string]string{
+			"CONFIG_TOKEN":      "t0ken",
+			"CONFIG_TOKEN_FILE": secretFile,
+		}))
+		var loadErr *LoadError
+		require.ErrorAs(t, err, &loadErr)
+		assert.Equal(t, "token", loadErr.Errors[0].Path)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := Load(&secretConfig{}, envMap(map[string]string{
+			"CONFIG_TOKEN_FILE": filepath.Join(dir, "missing"),
+		}))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+type secretPin struct {
+	Pin      int    `json:"pin" secret:"true"`
+	Password string `json:"password" secret:"true" validate:"min=8"`
+}
+
+func TestSecretErrorsHideValue(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		err := Load(&secretPin{}, envMap(map[string]string{"CONFIG_PIN": "12x4"}))
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "12x4")
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"pin": "12x4"}`)
+		err := Load(&secretPin{}, WithFile(path), envMap(nil))
+		var loadErr *LoadError
+		require.ErrorAs(t, err, &loadErr)
+		assert.Equal(t, "pin", loadErr.Errors[0].Path)
+		assert.NotContains(t, err.Error(), "12x4")
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		err := Validate(&secretPin{Password: "hunter2"})
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.Len(t, validationErr.Errors, 1)
+		assert.Equal(t, "min", validationErr.Errors[0].Rule)
+		assert.Equal(t, redactedText, validationErr.Errors[0].Value)
+		assert.NotContains(t, err.Error(), "hunter2")
+	})
+}
+
+func TestSecretDecryption(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "config.key")
+	require.NoError(t, GenerateKeyFile(keyFile))
+	key, err := ReadKeyFile(keyFile)
+	require.NoError(t, err)
+
+	encPassword, err := EncryptSecret(key, "hunter2")
+	require.NoError(t, err)
+	encToken, err := EncryptSecret(key, "t0ken")
+	require.NoError(t, err)
+	assert.NotContains(t, encPassword, "hunter2")
+
+	path := writeFile(t, "config.json", fmt.Sprintf(`{"db": {"password": %q}, "name": %q}`, encPassword, encToken))
+	env := envMap(map[string]string{"CONFIG_TOKEN": encToken})
+
+	var cfg secretConfig
+	require.NoError(t, Load(&cfg, WithFile(path), WithKeyFile(keyFile), env))
+	assert.Equal(t, "hunter2", cfg.DB.Password)
+	assert.Equal(t, "t0ken", cfg.Token)
+	assert.Equal(t, encToken, cfg.Name, "only secret fields are decrypted")
+
+	t.Run("no key file", func(t *testing.T) {
+		err := Load(&secretConfig{}, WithFile(path), env)
+		assert.True(t, errors.Is(err, NoKeyFileError), "got %v", err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherKey := filepath.Join(t.TempDir(), "other.key")
+		require.NoError(t, GenerateKeyFile(otherKey))
+		err := Load(&secretConfig{}, WithFile(path), WithKeyFile(otherKey), env)
+		assert.ErrorIs(t, err, InvalidCiphertextError)
+	})
+
+	t.Run("invalid key file", func(t *testing.T) {
+		err := Load(&secretConfig{}, WithKeyFile(writeFile(t, "bad.key", "short")), envMap(nil))
+		assert.ErrorIs(t, err, InvalidKeyError)
+	})
+}
+
+func TestSecretsInSettingsAndChanges(t *testing.T) {
+	settings, err := Settings(&secretConfig{}, envMap(map[string]string{"CONFIG_DB_PASSWORD": "hunter2"}))
+	require.NoError(t, err)
+	for _, s := range settings {
+		if s.Path == "db.password" {
+			assert.Equal(t, redactedText, s.Value)
+			assert.Equal(t, SourceEnv, s.Source)
+		}
+	}
+
+	var changes []FieldChange
+	collectChanges("", reflect.ValueOf(secretConfig{Token: "a"}), reflect.ValueOf(secretConfig{Token: "b"}), &changes)
+	assert.Equal(t, []FieldChange{{Path: "token", Old: redactedText, New: redactedText}}, changes)
+}
+
+type secretReplicas struct {
+	Name     string              `json:"name"`
+	Replicas []SecretDB          `json:"replicas"`
+	Shards   map[string]SecretDB `json:"shards"`
+	Extra    any                 `json:"extra"`
+}
+
+func TestRedactNestedInCollections(t *testing.T) {
+	cfg := secretReplicas{
+		Name:     "app",
+		Replicas: []SecretDB{{User: "r1", Password: "hunter2"}, {User: "r2"}},
+		Shards:   map[string]SecretDB{"b": {User: "s2", Password: "swordfish"}, "a": {User: "s1"}},
+		Extra:    []any{&SecretDB{User: "x", Password: "letmein"}},
+	}
+
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		out := fmt.Sprintf(format, Redact(&cfg))
+		for _, secret := range []string{"hunter2", "swordfish", "letmein"} {
+			assert.NotContains(t, out, secret, format)
+		}
+	}
+	assert.Equal(t, "{name:app replicas:[{user:r1 password:******} {user:r2 password:}] "+
+		"shards:map[a:{user:s1 password:} b:{user:s2 password:******}] extra:[{user:x password:******}]}", Redact(&cfg).String())
+
+	data, err := json.Marshal(Redact(&cfg))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "app",
+		"replicas": [{"user": "r1", "password": "******"}, {"user": "r2", "password": ""}],
+		"shards": {"a": {"user": "s1", "password": ""}, "b": {"user": "s2", "password": "******"}},
+		"extra": [{"user": "x", "password": "******"}]
+	}`, string(data))
+
+	t.Run("nil collections", func(t *testing.T) {
+		data, err := json.Marshal(Redact(secretReplicas{}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "", "replicas": null, "shards": null, "extra": null}`, string(data))
+		assert.Equal(t, "{name: replicas:[] shards:map[] extra:<nil>}", Redact(secretReplicas{}).String())
+	})
+
+	t.Run("settings and changes", func(t *testing.T) {
+		settings, err := Settings(&cfg, envMap(nil))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, PrintSettings(&buf, settings))
+		assert.NotContains(t, buf.String(), "hunter2")
+		assert.NotContains(t, buf.String(), "swordfish")
+		assert.NotContains(t, buf.String(), "letmein")
+		assert.Contains(t, buf.String(), "[{user:r1 password:******} {user:r2 password:}]")
+
+		next := cfg
+		next.Replicas = []SecretDB{{User: "r1", Password: "changed"}}
+		next.Shards = map[string]SecretDB{"a": {User: "s1", Password: "changed"}}
+		var changes []FieldChange
+		collectChanges("", reflect.ValueOf(cfg), reflect.ValueOf(next), &changes)
+		require.Len(t, changes, 2)
+		for _, c := range changes {
+			out := fmt.Sprint(c.Old, c.New)
+			assert.NotContains(t, out, "hunter2", c.Path)
+			assert.NotContains(t, out, "swordfish", c.Path)
+			assert.NotContains(t, out, "changed", c.Path)
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package reflects
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidatorFunc 校验函数，v 为字段值（指针已解引用），param 为 tag 中 '=' 之后的参数。
+// 校验失败时返回的错误信息会直接展示给用户，例如 "must be at least 1"。
+type ValidatorFunc func(v reflect.Value, param string) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{
+		"min": validateMin,
+		"max": validateMax,
+		"len": validateLen,
+		"oneof": func(v reflect.Value, param string) error {
+			s := fmt.Sprint(v.Interface())
+			for _, option := range strings.Fields(param) {
+				if s == option {
+					return nil
+				}
+			}
+			return fmt.Errorf("must be one of [%s]", param)
+		},
+		"ip":   validateIP(func(ip net.IP) bool { return true }, "a valid IP address"),
+		"ipv4": validateIP(func(ip net.IP) bool { return ip.To4() != nil }, "a valid IPv4 address"),
+		"ipv6": validateIP(func(ip net.IP) bool { return ip.To4() == nil }, "a valid IPv6 address"),
+		"url": func(v reflect.Value, _ string) error {
+			u, err := url.Parse(fmt.Sprint(v.Interface()))
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return errors.New("must be an absolute URL")
+			}
+			return nil
+		},
+	}
+)
+
+// RegisterValidator 注册自定义校验规则，同名规则会被覆盖。required 和 omitempty 为内置关键字，不能覆盖。
+func RegisterValidator(name string, fn ValidatorFunc) {
+	if name == "" || name == "required" || name == "omitempty" || fn == nil {
+		panic(fmt.Sprintf("config: invalid validator %q", name))
+	}
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+func lookupValidator(name string) (ValidatorFunc, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	fn, ok := validators[name]
+	return fn, ok
+}
+
+// ValidationFieldError 单个字段的校验错误，同时给出配置文件中的键和对应的环境变量，方便用户修正
+type ValidationFieldError struct {
+	Path  string
+	Env   string
+	Rule  string
+	Value any
+	Err   error
+}
+
+func (e *ValidationFieldError) Error() string {
+	return fmt.Sprintf("%s %v (set %q in the config file or %s)", e.Path, e.Err, e.Path, e.Env)
+}
+
+func (e *ValidationFieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError 汇总所有字段的校验错误
+type ValidationError struct {
+	Errors []*ValidationFieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "config: invalid " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate 按 validate tag 校验结构体，例如 `validate:"required,min=1,max=65535"`。
+// 规则依次执行，omitempty 表示零值时跳过后续规则。opts 中的环境变量前缀用于生成错误提示。
+func Validate(ptr any, opts ...Option) error {
+	v := reflect.Indirect(reflect.ValueOf(ptr))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("config: Validate expects a struct or pointer to struct, got %T", ptr)
+	}
+
+	l := &loader{envPrefix: "CONFIG"}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	var errs []*ValidationFieldError
+	validateStruct(v, "", l.envPrefix, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, path, env string, errs *[]*ValidationFieldError) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		fpath, fenv := joinPath(path, key), joinEnv(env, key)
+		if sf.Anonymous && !hasJSONName(sf) {
+			fpath, fenv = path, env
+		}
+
+		if tag, ok := sf.Tag.Lookup("validate"); ok {
+			if !validateField(fv, tag, fpath, fenv, isSecret(sf), errs) {
+				continue
+			}
+		}
+
+		if isNestedStruct(sf.Type) {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			validateStruct(fv, fpath, fenv, errs)
+		}
+	}
+}
+
+// validateField 执行字段上的所有规则，返回 false 表示字段已经出错，不再深入校验其内部。
+// secret 字段的 Value 为 ******，为空时保持为 nil。
+func validateField(v reflect.Value, tag, path, env string, secret bool, errs *[]*ValidationFieldError) bool {
+	fail := func(rule string, err error) {
+		var value any
+		switch {
+		case secret:
+			if !v.IsZero() {
+				value = redactedText
+			}
+		case v.CanInterface():
+			value = v.Interface()
+		}
+		*errs = append(*errs, &ValidationFieldError{Path: path, Env: env, Rule: rule, Value: value, Err: err})
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			if v.IsZero() {
+				fail(name, errors.New("is required"))
+				return false
+			}
+			continue
+		case "omitempty":
+			if v.IsZero() {
+				return true
+			}
+			continue
+		}
+
+		target := v
+		for target.Kind() == reflect.Pointer {
+			if target.IsNil() {
+				return true
+			}
+			target = target.Elem()
+		}
+
+		fn, ok := lookupValidator(name)
+		if !ok {
+			fail(name, fmt.Errorf("has unknown validator %q", name))
+			return false
+		}
+		if err := fn(target, param); err != nil {
+			fail(name, err)
+			return false
+		}
+	}
+	return true
+}
+
+func validateMin(v reflect.Value, param string) error {
+	c, err := compare(v, param)
+	if err != nil {
+		return err
+	}
+	if c < 0 {
+		return fmt.Errorf("must be at least %s", param)
+	}
+	return nil
+}
+
+func validateMax(v reflect.Value, param string) error {
+	c, err := compare(v, param)
+	if err != nil {
+		return err
+	}
+	if c > 0 {
+		return fmt.Errorf("must be at most %s", param)
+	}
+	return nil
+}
+
+func validateLen(v reflect.Value, param string) error {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return fmt.Errorf("has invalid len parameter %q", param)
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() != n {
+			return fmt.Errorf("must have length %d", n)
+		}
+		return nil
+	}
+	return fmt.Errorf("does not support len on %s", v.Type())
+}
+
+// compare 比较 v 与 param：数字比较数值，time.Duration 比较时长，字符串/切片/map 比较长度
+func compare(v reflect.Value, param string) (int, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return 0, fmt.Errorf("has invalid duration parameter %q", param)
+		}
+		return compareFloat(float64(v.Int()), float64(d)), nil
+	}
+
+	var actual float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		actual = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = v.Float()
+	case reflect.String:
+		actual = float64(len([]rune(v.String())))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual = float64(v.Len())
+	default:
+		return 0, fmt.Errorf("does not support min/max on %s", v.Type())
+	}
+
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("has invalid parameter %q", param)
+	}
+	return compareFloat(actual, limit), nil
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func validateIP(accept func(net.IP) bool, want string) ValidatorFunc {
+	return func(v reflect.Value, _ string) error {
+		var ip net.IP
+		switch {
+		case v.Type() == reflect.TypeOf(net.IP{}):
+			ip = v.Interface().(net.IP)
+		case v.Kind() == reflect.String:
+			ip = net.ParseIP(v.String())
+		default:
+			return fmt.Errorf("does not support ip on %s", v.Type())
+		}
+		if ip == nil || !accept(ip) {
+			return fmt.Errorf("must be %s", want)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,145 @@
+package reflects
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type UpstreamConfig struct {
+	URL     string        `json:"url" validate:"required,url"`
+	Timeout time.Duration `json:"timeout" validate:"min=100ms,max=1m"`
+}
+
+type ServerConfig struct {
+	Name     string            `json:"server-name" validate:"required,min=2,max=16"`
+	Ip       string            `json:"server-ip" validate:"required,ip"`
+	Port     int               `json:"server-port" validate:"min=1,max=65535"`
+	Mode     string            `json:"mode" validate:"oneof=dev prod"`
+	Admin    string            `json:"admin" validate:"omitempty,ipv4"`
+	Tags     []string          `json:"tags" validate:"max=2"`
+	Upstream *UpstreamConfig   `json:"upstream"`
+	Backup   *UpstreamConfig   `json:"backup" validate:"required"`
+	Extra    map[string]string `json:"extra" validate:"len=0"`
+}
+
+func validServerConfig() ServerConfig {
+	return ServerConfig{
+		Name:     "core",
+		Ip:       "127.0.0.1",
+		Port:     8080,
+		Mode:     "dev",
+		Upstream: &UpstreamConfig{URL: "http://up.local", Timeout: time.Second},
+		Backup:   &UpstreamConfig{URL: "http://backup.local", Timeout: time.Second},
+	}
+}
+
+func validationFields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), "got %v", err)
+
+	got := map[string]string{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = fe.Rule
+	}
+	return got
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg := validServerConfig()
+		assert.NoError(t, Validate(&cfg))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg := validServerConfig()
+		cfg.Name = "x"
+		cfg.Ip = "300.1.1.1"
+		cfg.Port = 70000
+		cfg.Mode = "test"
+		cfg.Admin = "::1"
+		cfg.Tags = []string{"a", "b", "c"}
+		cfg.Upstream.URL = "not a url"
+		cfg.Upstream.Timeout = time.Millisecond
+		cfg.Backup = nil
+		cfg.Extra = map[string]string{"a": "b"}
+
+		assert.Equal(t, map[string]string{
+			"server-name":  "min",
+			"server-ip":    "ip",
+			"server-port":  "max",
+			"mode":         "oneof",
+			"admin":        "ipv4",
+			"tags":         "max",
+			"upstream.url": "url",
+			// 同一字段只报告第一条失败的规则
+			"upstream.timeout": "min",
+			"backup":           "required",
+			"extra":            "len",
+		}, validationFields(t, Validate(&cfg)))
+	})
+
+	t.Run("required stops further rules", func(t *testing.T) {
+		cfg := validServerConfig()
+		cfg.Ip = ""
+		assert.Equal(t, map[string]string{"server-ip": "required"}, validationFields(t, Validate(cfg)))
+	})
+
+	t.Run("message names json key and env var", func(t *testing.T) {
+		cfg := validServerConfig()
+		cfg.Port = 0
+		cfg.Upstream.Timeout = time.Hour
+
+		err := Validate(&cfg, WithEnvPrefix("APP"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `server-port must be at least 1 (set "server-port" in the config file or APP_SERVER_PORT)`)
+		assert.Contains(t, err.Error(), `upstream.timeout must be at most 1m (set "upstream.timeout" in the config file or APP_UPSTREAM_TIMEOUT)`)
+	})
+
+	t.Run("unknown validator", func(t *testing.T) {
+		cfg := struct {
+			Name string `json:"name" validate:"shiny"`
+		}{"x"}
+		assert.Equal(t, map[string]string{"name": "shiny"}, validationFields(t, Validate(&cfg)))
+	})
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("lowercase", func(v reflect.Value, _ string) error {
+		if v.String() != strings.ToLower(v.String()) {
+			return fmt.Errorf("must be lowercase")
+		}
+		return nil
+	})
+
+	cfg := struct {
+		Name string `json:"name" validate:"lowercase"`
+	}{"Core"}
+	err := Validate(&cfg)
+	assert.Equal(t, map[string]string{"name": "lowercase"}, validationFields(t, err))
+	assert.Contains(t, err.Error(), "name must be lowercase")
+
+	assert.Panics(t, func() { RegisterValidator("required", func(reflect.Value, string) error { return nil }) })
+}
+
+func TestLoadValidates(t *testing.T) {
+	var cfg Config
+	err := Load(&cfg, envMap(map[string]string{
+		"CONFIG_SERVER_NAME": "core",
+		"CONFIG_SERVER_IP":   "localhost",
+		"CONFIG_SERVER_PORT": "0",
+	}))
+
+	assert.Equal(t, map[string]string{
+		"server-ip":   "ip",
+		"server-port": "min",
+	}, validationFields(t, err))
+	assert.Contains(t, err.Error(), "CONFIG_SERVER_IP")
+}
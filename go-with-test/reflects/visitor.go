@@ -0,0 +1,238 @@
+package reflects
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+var (
+	// SkipChildren 由 VisitFunc 返回，表示不再深入当前节点，继续遍历其兄弟节点
+	SkipChildren = errors.New("skip children")
+	// SkipAll 由 VisitFunc 返回，表示立即结束遍历，Walk 返回 nil
+	SkipAll = errors.New("skip all")
+)
+
+// Node Walk 访问到的一个值
+type Node struct {
+	// Path 从根开始的路径，例如 a.b[3].c，map 的键写作 m[key]，根节点为空串。
+	// 指针和接口不会产生新的路径段，它们和指向的值共享同一个 Path。
+	Path  string
+	Value reflect.Value
+	Depth int
+	// Field 当前值所在的结构体字段，不是结构体字段时为 nil
+	Field *reflect.StructField
+	// Cycle 为 true 表示该值指向正在遍历的祖先节点，Walk 不会再深入
+	Cycle bool
+}
+
+// VisitFunc 对每个节点调用一次，返回 SkipChildren/SkipAll 控制遍历，返回其它错误时 Walk 立即返回该错误
+type VisitFunc func(n Node) error
+
+// WalkOption Walk 的可选项
+type WalkOption func(*walker)
+
+// WithChanDrain 遍历 channel 时最多非阻塞地读取 limit 个元素，默认不读取。
+// 读取会真正地从 channel 中取走元素，只适合用于调试或已经不再使用的 channel。
+func WithChanDrain(limit int) WalkOption {
+	return func(w *walker) {
+		w.chanLimit = limit
+	}
+}
+
+// Walk 深度优先遍历 x，先访问节点本身再访问其子节点：
+// 结构体的字段（包括未导出字段）、切片和数组的元素、map 的值（按键排序）、
+// 指针和接口指向的值，以及 channel 中的元素（见 WithChanDrain）。函数只作为叶子节点访问。
+// 通过指针、map 或切片形成的环只会访问一次，再次遇到时以 Cycle 为 true 的节点报告。
+func Walk(x any, fn VisitFunc, opts ...WalkOption) error {
+	w := &walker{fn: fn, visiting: map[visitKey]bool{}}
+	for _, opt := range opts {
+		opt(w)
+	}
+	err := w.walk(Node{Value: reflect.ValueOf(x)})
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	fn        VisitFunc
+	chanLimit int
+	// visiting 当前路径上的引用类型，用于发现环
+	visiting map[visitKey]bool
+}
+
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (w *walker) walk(n Node) error {
+	v := n.Value
+	key, ref := refKey(v)
+	if ref && w.visiting[key] {
+		n.Cycle = true
+		return skipChildren(w.fn(n))
+	}
+	if err := w.fn(n); err != nil {
+		return skipChildren(err)
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if ref {
+		w.visiting[key] = true
+		defer delete(w.visiting, key)
+	}
+
+	child := func(path string, v reflect.Value, field *reflect.StructField) Node {
+		return Node{Path: path, Value: v, Depth: n.Depth + 1, Field: field}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return w.walk(child(n.Path, v.Elem(), nil))
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if err := w.walk(child(joinPath(n.Path, sf.Name), v.Field(i), &sf)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := w.walk(child(n.Path+"["+strconv.Itoa(i)+"]", v.Index(i), nil)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compareValues)
+		for _, k := range keys {
+			if err := w.walk(child(n.Path+"["+formatKey(k)+"]", v.MapIndex(k), nil)); err != nil {
+				return err
+			}
+		}
+	case reflect.Chan:
+		// 未导出字段中的 channel 无法通过反射读取
+		if v.IsNil() || v.Type().ChanDir()&reflect.RecvDir == 0 || !v.CanInterface() {
+			return nil
+		}
+		for i := 0; i < w.chanLimit; i++ {
+			item, ok := v.TryRecv()
+			if !ok {
+				break
+			}
+			if err := w.walk(child(n.Path+"["+strconv.Itoa(i)+"]", item, nil)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipChildren 在节点已经访问过后，SkipChildren 等价于正常返回
+func skipChildren(err error) error {
+	if errors.Is(err, SkipChildren) {
+		return nil
+	}
+	return err
+}
+
+// refKey 返回可能形成环的引用类型值的标识
+func refKey(v reflect.Value) (visitKey, bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map:
+		if v.IsNil() {
+			return visitKey{}, false
+		}
+		return visitKey{ptr: v.Pointer(), typ: v.Type()}, true
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return visitKey{}, false
+		}
+		return visitKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}, true
+	}
+	return visitKey{}, false
+}
+
+func formatKey(k reflect.Value) string {
+	for k.Kind() == reflect.Interface && !k.IsNil() {
+		k = k.Elem()
+	}
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if k.CanInterface() {
+		return fmt.Sprint(k.Interface())
+	}
+	return fmt.Sprintf("<%s>", k.Type())
+}
+
+// compareValues 为 map 的键定义一个确定的顺序：同类型按值比较，不同类型（接口类型的键）先按类型名比较
+func compareValues(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() || a.Kind() == reflect.Interface || b.Kind() == reflect.Interface {
+		// nil 接口排在最前面
+		return cmp.Compare(boolInt(a.IsValid() && a.Kind() != reflect.Interface), boolInt(b.IsValid() && b.Kind() != reflect.Interface))
+	}
+	if a.Type() != b.Type() {
+		return cmp.Compare(a.Type().String(), b.Type().String())
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Bool:
+		return cmp.Compare(boolInt(a.Bool()), boolInt(b.Bool()))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return cmp.Compare(uint64(a.Pointer()), uint64(b.Pointer()))
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareValues(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareValues(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Complex64, reflect.Complex128:
+		if c := cmp.Compare(real(a.Complex()), real(b.Complex())); c != 0 {
+			return c
+		}
+		return cmp.Compare(imag(a.Complex()), imag(b.Complex()))
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
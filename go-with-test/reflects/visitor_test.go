@@ -0,0 +1,192 @@
+package reflects
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type treeNode struct {
+	Name     string
+	Children []*treeNode
+	Parent   *treeNode
+	Meta     map[string]any
+}
+
+// visitLog 记录访问过的节点，格式为 path=kind
+func visitLog(t *testing.T, x any, opts ...WalkOption) []string {
+	t.Helper()
+	var got []string
+	require.NoError(t, Walk(x, func(n Node) error {
+		entry := fmt.Sprintf("%s=%s", n.Path, n.Value.Kind())
+		if n.Cycle {
+			entry += "(cycle)"
+		}
+		got = append(got, entry)
+		return nil
+	}, opts...))
+	return got
+}
+
+func TestWalkPaths(t *testing.T) {
+	x := struct {
+		A struct {
+			B []struct{ C string }
+		}
+		M map[string]int
+	}{}
+	x.A.B = make([]struct{ C string }, 2)
+	x.M = map[string]int{"z": 1, "a": 2}
+
+	assert.Equal(t, []string{
+		"=struct",
+		"A=struct",
+		"A.B=slice",
+		"A.B[0]=struct",
+		"A.B[0].C=string",
+		"A.B[1]=struct",
+		"A.B[1].C=string",
+		"M=map",
+		"M[a]=int",
+		"M[z]=int",
+	}, visitLog(t, x))
+}
+
+func TestWalkKinds(t *testing.T) {
+	n := 42
+	p := &n
+	var iface fmt.Stringer
+	ch := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		ch <- i
+	}
+
+	x := struct {
+		PP    **int
+		Any   any
+		Nil   fmt.Stringer
+		Fn    func()
+		Ch    chan int
+		Arr   [2]bool
+		inner string
+	}{PP: &p, Any: &n, Nil: iface, Fn: func() {}, Ch: ch, inner: "x"}
+
+	assert.Equal(t, []string{
+		"=struct",
+		"PP=ptr",
+		"PP=ptr",
+		"PP=int",
+		"Any=interface",
+		"Any=ptr",
+		"Any=int",
+		"Nil=interface",
+		"Fn=func",
+		"Ch=chan",
+		"Ch[0]=int",
+		"Ch[1]=int",
+		"Ch[2]=int",
+		"Arr=array",
+		"Arr[0]=bool",
+		"Arr[1]=bool",
+		"inner=string",
+	}, visitLog(t, x, WithChanDrain(3)))
+	assert.Len(t, ch, 2, "drain stops at the limit")
+
+	t.Run("without drain channels are left alone", func(t *testing.T) {
+		visitLog(t, x)
+		assert.Len(t, ch, 2)
+	})
+
+	t.Run("nil root", func(t *testing.T) {
+		assert.Equal(t, []string{"=invalid"}, visitLog(t, nil))
+	})
+}
+
+func TestWalkCycles(t *testing.T) {
+	root := &treeNode{Name: "root"}
+	child := &treeNode{Name: "child", Parent: root}
+	root.Children = []*treeNode{child}
+	root.Meta = map[string]any{}
+	root.Meta["self"] = root.Meta
+
+	got := visitLog(t, root)
+	assert.Contains(t, got, "Children[0].Parent=ptr(cycle)")
+	assert.Contains(t, got, "Meta[self]=map(cycle)")
+	assert.NotContains(t, got, "Children[0].Parent.Name=string")
+
+	t.Run("shared values that do not form a cycle are visited each time", func(t *testing.T) {
+		shared := &Profile{City: "London"}
+		got := visitLog(t, []*Profile{shared, shared})
+		assert.Contains(t, got, "[0].City=string")
+		assert.Contains(t, got, "[1].City=string")
+		assert.NotContains(t, got, "[1]=ptr(cycle)")
+	})
+}
+
+func TestWalkSkip(t *testing.T) {
+	x := Person{Name: "Chris", Profile: Profile{Age: 33, City: "London"}}
+
+	t.Run("skip children", func(t *testing.T) {
+		var got []string
+		require.NoError(t, Walk(x, func(n Node) error {
+			got = append(got, n.Path)
+			if n.Field != nil && n.Field.Name == "Profile" {
+				return SkipChildren
+			}
+			return nil
+		}))
+		assert.Equal(t, []string{"", "Name", "Profile"}, got)
+	})
+
+	t.Run("skip all", func(t *testing.T) {
+		var got []string
+		require.NoError(t, Walk(x, func(n Node) error {
+			got = append(got, n.Path)
+			if n.Path == "Profile.Age" {
+				return SkipAll
+			}
+			return nil
+		}))
+		assert.Equal(t, []string{"", "Name", "Profile", "Profile.Age"}, got)
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		boom := errors.New("boom")
+		err := Walk(x, func(n Node) error {
+			if n.Value.Kind() == reflect.Int {
+				return boom
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, boom)
+	})
+}
+
+func TestWalkMapOrder(t *testing.T) {
+	m := map[any]string{
+		3:     "c",
+		1:     "a",
+		"b":   "s",
+		2.5:   "f",
+		false: "no",
+		true:  "yes",
+		nil:   "nil",
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, []string{
+			"=map",
+			"[<nil>]=string",
+			"[false]=string",
+			"[true]=string",
+			"[2.5]=string",
+			"[1]=string",
+			"[3]=string",
+			"[b]=string",
+		}, visitLog(t, m))
+	}
+}
@@ -0,0 +1,336 @@
+package reflects
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FieldChange 一次重载中某个叶子字段的变化，Path 使用配置键，例如 db.port。
+// secret 字段的 Old/New 为 ******，内部含有 secret 字段的值（例如结构体切片）为 Redacted，
+// 需要新值时从 ChangeEvent.New 中读取。
+type FieldChange struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// ChangeEvent 配置变更事件，Old/New 都是只读快照
+type ChangeEvent[T any] struct {
+	Old     *T
+	New     *T
+	Changes []FieldChange
+}
+
+// Changed 判断 path 或其下任意子字段是否发生了变化
+func (e ChangeEvent[T]) Changed(path string) bool {
+	for _, c := range e.Changes {
+		if c.Path == path || strings.HasPrefix(c.Path, path+".") || strings.HasPrefix(c.Path, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchOption Watcher 的可选项
+type WatchOption func(*watchSettings)
+
+type watchSettings struct {
+	loadOptions  []Option
+	pollInterval time.Duration
+	polling      bool
+	debounce     time.Duration
+	signals      []os.Signal
+	onError      func(error)
+}
+
+// WithLoadOptions 每次加载时传给 Load 的选项，例如环境变量前缀
+func WithLoadOptions(opts ...Option) WatchOption {
+	return func(s *watchSettings) {
+		s.loadOptions = append(s.loadOptions, opts...)
+	}
+}
+
+// WithPolling 不使用 fsnotify，强制通过轮询文件的修改时间和大小发现变更
+func WithPolling(interval time.Duration) WatchOption {
+	return func(s *watchSettings) {
+		s.polling, s.pollInterval = true, interval
+	}
+}
+
+// WithReloadSignals 收到这些信号时重新加载，默认为 SIGHUP
+func WithReloadSignals(sigs ...os.Signal) WatchOption {
+	return func(s *watchSettings) {
+		s.signals = sigs
+	}
+}
+
+// WithErrorHandler 后台重载失败时的回调，失败时继续沿用旧配置
+func WithErrorHandler(fn func(error)) WatchOption {
+	return func(s *watchSettings) {
+		s.onError = fn
+	}
+}
+
+// Watcher 监听配置文件，变更时重新加载、校验并原子替换当前配置。
+// 新配置加载或校验失败时保留旧配置，不会通知订阅者。
+type Watcher[T any] struct {
+	path     string
+	settings watchSettings
+	current  atomic.Pointer[T]
+
+	// reloadMu 保证同一时刻只有一次重载，事件按顺序发出
+	reloadMu sync.Mutex
+
+	subMu   sync.Mutex
+	subs    map[uint64]func(ChangeEvent[T])
+	nextSub uint64
+}
+
+// NewWatcher 立即加载一次配置，失败时返回错误。调用 Run 后才开始监听变更。
+func NewWatcher[T any](path string, opts ...WatchOption) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		path: path,
+		settings: watchSettings{
+			pollInterval: time.Second,
+			debounce:     50 * time.Millisecond,
+			signals:      []os.Signal{syscall.SIGHUP},
+			onError:      func(error) {},
+		},
+		subs: map[uint64]func(ChangeEvent[T]){},
+	}
+	for _, opt := range opts {
+		opt(&w.settings)
+	}
+
+	cfg, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.current.Store(cfg)
+	return w, nil
+}
+
+// Current 返回当前配置快照。快照在替换后不会再被修改，调用方也不应修改它。
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe 订阅配置变更，回调在重载的协程中同步执行。返回的函数用于取消订阅。
+func (w *Watcher[T]) Subscribe(fn func(ChangeEvent[T])) (unsubscribe func()) {
+	w.subMu.Lock()
+	defer w.subMu.Unlock()
+
+	id := w.nextSub
+	w.nextSub++
+	w.subs[id] = fn
+	return func() {
+		w.subMu.Lock()
+		defer w.subMu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Reload 重新加载配置文件，通过校验后替换当前配置并通知订阅者
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next, err := w.load()
+	if err != nil {
+		return err
+	}
+	old := w.current.Swap(next)
+
+	var changes []FieldChange
+	collectChanges("", reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem(), &changes)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	event := ChangeEvent[T]{Old: old, New: next, Changes: changes}
+	w.subMu.Lock()
+	subs := make([]func(ChangeEvent[T]), 0, len(w.subs))
+	for _, fn := range w.subs {
+		subs = append(subs, fn)
+	}
+	w.subMu.Unlock()
+
+	for _, fn := range subs {
+		fn(event)
+	}
+	return nil
+}
+
+// Run 监听文件变更和重载信号，直到 ctx 结束。
+// 优先使用 fsnotify，初始化失败（例如 inotify 数量耗尽）时退化为轮询。
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	sigCh := make(chan os.Signal, 1)
+	if len(w.settings.signals) > 0 {
+		signal.Notify(sigCh, w.settings.signals...)
+		defer signal.Stop(sigCh)
+	}
+
+	changed, stop := w.watchFile()
+	defer stop()
+
+	reload := func() {
+		if err := w.Reload(); err != nil {
+			w.settings.onError(err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sigCh:
+			reload()
+		case <-changed:
+			reload()
+		}
+	}
+}
+
+func (w *Watcher[T]) load() (*T, error) {
+	cfg := new(T)
+	opts := append(append([]Option(nil), w.settings.loadOptions...), WithFile(w.path))
+	if err := Load(cfg, opts...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// watchFile 返回文件变更通知的 channel 以及停止监听的函数
+func (w *Watcher[T]) watchFile() (<-chan struct{}, func()) {
+	if !w.settings.polling {
+		ch, stop, err := w.watchFSNotify()
+		if err == nil {
+			return ch, stop
+		}
+		w.settings.onError(err)
+	}
+	return w.watchPolling()
+}
+
+func (w *Watcher[T]) watchFSNotify() (<-chan struct{}, func(), error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	// 监听目录而不是文件本身：编辑器保存时常常是写临时文件再 rename，直接监听文件会丢失后续事件
+	if err := fw.Add(filepath.Dir(w.path)); err != nil {
+		fw.Close()
+		return nil, nil, err
+	}
+
+	target := filepath.Clean(w.path)
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		// 合并短时间内的多次写入
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) == target && !ev.Has(fsnotify.Chmod) {
+					debounce = time.After(w.settings.debounce)
+				}
+			case err, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+				w.settings.onError(err)
+			case <-debounce:
+				debounce = nil
+				notify(changed)
+			}
+		}
+	}()
+
+	return changed, func() {
+		close(done)
+		fw.Close()
+	}, nil
+}
+
+func (w *Watcher[T]) watchPolling() (<-chan struct{}, func()) {
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.settings.pollInterval)
+		defer ticker.Stop()
+
+		last, _ := os.Stat(w.path)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(w.path)
+				if err != nil {
+					continue
+				}
+				if last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size() {
+					last = info
+					notify(changed)
+				}
+			}
+		}
+	}()
+	return changed, func() { close(done) }
+}
+
+// notify 非阻塞地发送通知，已有未处理的通知时直接丢弃
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// collectChanges 逐字段比较两个配置，结构体会展开到叶子字段
+func collectChanges(path string, old, next reflect.Value, out *[]FieldChange) {
+	if old.Kind() == reflect.Pointer && next.Kind() == reflect.Pointer && !old.IsNil() && !next.IsNil() && isNestedStruct(old.Type()) {
+		old, next = old.Elem(), next.Elem()
+	}
+	if old.Kind() != reflect.Struct || !isNestedStruct(old.Type()) {
+		if !reflect.DeepEqual(old.Interface(), next.Interface()) {
+			*out = append(*out, FieldChange{Path: path, Old: redactedValue(old), New: redactedValue(next)})
+		}
+		return
+	}
+
+	typ := old.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		key, ok := fieldKey(sf)
+		if !ok {
+			continue
+		}
+		fpath := joinPath(path, key)
+		if sf.Anonymous && !hasJSONName(sf) {
+			fpath = path
+		}
+		if isSecret(sf) {
+			if !reflect.DeepEqual(old.Field(i).Interface(), next.Field(i).Interface()) {
+				*out = append(*out, FieldChange{Path: fpath, Old: redactedText, New: redactedText})
+			}
+			continue
+		}
+		collectChanges(fpath, old.Field(i), next.Field(i), out)
+	}
+}
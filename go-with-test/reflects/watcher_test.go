@@ -0,0 +1,181 @@
+package reflects
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type watchedConfig struct {
+	Name string   `json:"name" validate:"required"`
+	Port int      `json:"port" validate:"min=1,max=65535"`
+	DB   DBConfig `json:"db"`
+}
+
+// eventRecorder 并发安全地记录收到的变更事件
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []ChangeEvent[watchedConfig]
+}
+
+func (r *eventRecorder) record(e ChangeEvent[watchedConfig]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func (r *eventRecorder) last() ChangeEvent[watchedConfig] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func newTestWatcher(t *testing.T, opts ...WatchOption) (*Watcher[watchedConfig], string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{"name": "v1", "port": 8080}`)
+
+	opts = append([]WatchOption{WithLoadOptions(envMap(nil))}, opts...)
+	w, err := NewWatcher[watchedConfig](path, opts...)
+	require.NoError(t, err)
+	return w, path
+}
+
+func runWatcher(t *testing.T, w *Watcher[watchedConfig]) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func TestWatcherReload(t *testing.T) {
+	w, path := newTestWatcher(t)
+	var events eventRecorder
+	w.Subscribe(events.record)
+
+	first := w.Current()
+	assert.Equal(t, "v1", first.Name)
+
+	writeConfig(t, path, `{"name": "v2", "port": 8080, "db": {"port": 6000}}`)
+	require.NoError(t, w.Reload())
+
+	assert.Equal(t, "v2", w.Current().Name)
+	assert.Equal(t, "v1", first.Name, "old snapshot must not be mutated")
+	require.Equal(t, 1, events.len())
+
+	e := events.last()
+	assert.Same(t, first, e.Old)
+	assert.Same(t, w.Current(), e.New)
+	assert.Equal(t, []FieldChange{
+		{Path: "name", Old: "v1", New: "v2"},
+		{Path: "db.port", Old: 5432, New: 6000},
+	}, e.Changes)
+	assert.True(t, e.Changed("db"))
+	assert.False(t, e.Changed("port"))
+
+	t.Run("unchanged file does not notify", func(t *testing.T) {
+		require.NoError(t, w.Reload())
+		assert.Equal(t, 1, events.len())
+	})
+
+	t.Run("invalid config is rejected", func(t *testing.T) {
+		writeConfig(t, path, `{"name": "v3", "port": 0}`)
+		err := w.Reload()
+
+		var verr *ValidationError
+		assert.ErrorAs(t, err, &verr)
+		assert.Equal(t, "v2", w.Current().Name)
+		assert.Equal(t, 1, events.len())
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		var other eventRecorder
+		unsubscribe := w.Subscribe(other.record)
+		unsubscribe()
+
+		writeConfig(t, path, `{"name": "v4", "port": 8080}`)
+		require.NoError(t, w.Reload())
+		assert.Equal(t, 0, other.len())
+		assert.Equal(t, 2, events.len())
+	})
+}
+
+func TestWatcherDetectsFileChanges(t *testing.T) {
+	modes := map[string][]WatchOption{
+		"fsnotify": nil,
+		"polling":  {WithPolling(10 * time.Millisecond)},
+	}
+
+	for name, opts := range modes {
+		t.Run(name, func(t *testing.T) {
+			var (
+				errMu sync.Mutex
+				errs  []error
+			)
+			opts := append(opts, WithReloadSignals(), WithErrorHandler(func(err error) {
+				errMu.Lock()
+				defer errMu.Unlock()
+				errs = append(errs, err)
+			}))
+			w, path := newTestWatcher(t, opts...)
+			runWatcher(t, w)
+
+			// fsnotify/轮询启动前的写入可能被错过，因此持续写入直到生效
+			require.Eventually(t, func() bool {
+				writeConfig(t, path, `{"name": "v2", "port": 9090}`)
+				return w.Current().Name == "v2"
+			}, 5*time.Second, 50*time.Millisecond)
+			assert.Equal(t, 9090, w.Current().Port)
+
+			writeConfig(t, path, `{"name": "", "port": 9090}`)
+			require.Eventually(t, func() bool {
+				errMu.Lock()
+				defer errMu.Unlock()
+				return len(errs) > 0
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, "v2", w.Current().Name)
+		})
+	}
+}
+
+func TestWatcherReloadsOnSignal(t *testing.T) {
+	w, path := newTestWatcher(t, WithPolling(time.Hour), WithReloadSignals(syscall.SIGUSR1))
+	runWatcher(t, w)
+	writeConfig(t, path, `{"name": "v2", "port": 8080}`)
+
+	// Run 注册信号之前收到的 SIGUSR1 会被忽略，因此重复发送直到生效
+	require.Eventually(t, func() bool {
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+		return w.Current().Name == "v2"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNewWatcherFailsOnInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{"port": 8080}`)
+
+	_, err := NewWatcher[watchedConfig](path, WithLoadOptions(envMap(nil)))
+	assert.Error(t, err)
+}
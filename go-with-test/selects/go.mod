@@ -1 +1,3 @@
 module selects
+
+go 1.27.1
@@ -0,0 +1,320 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var NoEndpointsError = errors.New("no endpoints to pick from")
+
+const (
+	defaultFailurePenalty = time.Second
+	defaultProbeTimeout   = 5 * time.Second
+)
+
+// EndpointStats 某个端点的统计，延迟与错误率都是指数加权移动平均 (EWMA)
+type EndpointStats struct {
+	URL       string
+	Latency   time.Duration
+	ErrorRate float64
+	InFlight  int
+	Requests  int
+	// LastSeen 最近一次有结果的时间，用于判断是否需要重新探测
+	LastSeen time.Time
+}
+
+// score 越小越好。没有数据的端点为 0，会被优先尝试；错误率与并发数会放大延迟
+func (s *EndpointStats) score() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Latency) * (1 + 10*s.ErrorRate) * float64(s.InFlight+1)
+}
+
+// Picker 根据历史延迟与错误率选择端点：随机取两个，选其中得分更好的 (power of two choices)。
+// 相比总是选最快的，它不会让所有客户端同时涌向同一个端点；相比随机选，又能避开慢的端点。
+type Picker struct {
+	// Alpha EWMA 中新样本的权重，默认 0.3
+	Alpha float64
+	// ProbeInterval 超过这个时间没有结果的端点会被重新探测，0 表示不探测
+	ProbeInterval time.Duration
+	// Probe 重新探测使用的方法，默认发送 GET 请求且 2xx 算成功
+	Probe func(ctx context.Context, endpoint string) error
+	// ProbeTimeout 单次探测的超时，默认 5s
+	ProbeTimeout time.Duration
+	// FailurePenalty 失败的请求至少按这个延迟计入，默认 1s。
+	// 挂掉的端点往往很快返回错误（连接被拒绝、503），不能因此显得比健康的端点更快。
+	FailurePenalty time.Duration
+	// Now 测试中可以替换
+	Now func() time.Time
+
+	mu    sync.Mutex
+	stats map[string]*EndpointStats
+	order []string
+	rand  *rand.Rand
+}
+
+// NewPicker 创建 Picker，endpoints 为基础 URL 如 http://mirror-1:8080 或 gRPC 的 host:port
+func NewPicker(endpoints ...string) *Picker {
+	p := &Picker{
+		stats: make(map[string]*EndpointStats),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, e := range endpoints {
+		p.stats[e] = &EndpointStats{URL: e}
+		p.order = append(p.order, e)
+	}
+	return p
+}
+
+func (p *Picker) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+func (p *Picker) failurePenalty() time.Duration {
+	if p.FailurePenalty <= 0 {
+		return defaultFailurePenalty
+	}
+	return p.FailurePenalty
+}
+
+func (p *Picker) probeTimeout() time.Duration {
+	if p.ProbeTimeout <= 0 {
+		return defaultProbeTimeout
+	}
+	return p.ProbeTimeout
+}
+
+func (p *Picker) alpha() float64 {
+	if p.Alpha <= 0 || p.Alpha > 1 {
+		return 0.3
+	}
+	return p.Alpha
+}
+
+// Pick 选择一个端点，返回的 done 必须在请求结束后调用一次，用于记录延迟与错误
+func (p *Picker) Pick() (endpoint string, done func(err error), err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.order)
+	if n == 0 {
+		return "", nil, NoEndpointsError
+	}
+	s := p.stats[p.order[p.rand.Intn(n)]]
+	if n > 1 {
+		i := p.rand.Intn(n - 1)
+		// 保证两次取到的不是同一个
+		if p.order[i] == s.URL {
+			i = n - 1
+		}
+		if other := p.stats[p.order[i]]; other.score() < s.score() {
+			s = other
+		}
+	}
+
+	s.InFlight++
+	start := p.now()
+	var once sync.Once
+	return s.URL, func(err error) {
+		once.Do(func() {
+			p.mu.Lock()
+			s.InFlight--
+			p.mu.Unlock()
+			p.Observe(s.URL, p.now().Sub(start), err)
+		})
+	}, nil
+}
+
+// Observe 记录一次请求的结果。err 为 context.Canceled 时 latency 只是下限：
+// 只有它比当前的平均延迟更大时才计入，不影响错误率。
+// 其他错误按不低于 FailurePenalty 与当前平均延迟的延迟计入，失败只会让端点显得更慢。
+func (p *Picker) Observe(endpoint string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.stats[endpoint]
+	if !ok {
+		return
+	}
+	a := p.alpha()
+	cancelled := errors.Is(err, context.Canceled)
+	if cancelled && s.Requests > 0 && latency <= s.Latency {
+		return
+	}
+
+	if err != nil && !cancelled {
+		if penalty := p.failurePenalty(); latency < penalty {
+			latency = penalty
+		}
+		if s.Requests > 0 && latency < s.Latency {
+			latency = s.Latency
+		}
+	}
+
+	if s.Requests == 0 {
+		s.Latency = latency
+	} else {
+		s.Latency = time.Duration(a*float64(latency) + (1-a)*float64(s.Latency))
+	}
+	if !cancelled {
+		failed := 0.0
+		if err != nil {
+			failed = 1
+		}
+		s.ErrorRate = a*failed + (1-a)*s.ErrorRate
+	}
+	s.Requests++
+	s.LastSeen = p.now()
+}
+
+// ObserveRace 把 Race 中每个发出过请求的候选的结果计入统计
+func (p *Picker) ObserveRace(res RaceResult) {
+	for _, c := range res.Candidates {
+		if c.Launched {
+			p.Observe(c.URL, c.Latency, c.Err)
+		}
+	}
+}
+
+// Stats 返回所有端点的统计，按得分从好到坏排序
+func (p *Picker) Stats() []EndpointStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]EndpointStats, 0, len(p.order))
+	for _, e := range p.order {
+		stats = append(stats, *p.stats[e])
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].score() < stats[j].score() })
+	return stats
+}
+
+// Do 选择端点后调用 fn 并记录结果，适用于 gRPC 等非 HTTP 客户端：
+//
+//	err := picker.Do(ctx, func(ctx context.Context, addr string) error {
+//		conn, err := grpc.DialContext(ctx, addr, ...)
+//		...
+//	})
+func (p *Picker) Do(ctx context.Context, fn func(ctx context.Context, endpoint string) error) error {
+	endpoint, done, err := p.Pick()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx, endpoint)
+	done(err)
+	return err
+}
+
+// Transport 返回一个 http.RoundTripper，把请求的 scheme 与 host 替换为选中的端点，
+// 端点带路径前缀时（如 http://mirror-1:8080/v2）拼接在请求路径之前，5xx 响应计为错误。
+// base 为 nil 时使用 http.DefaultTransport。
+func (p *Picker) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &pickerTransport{picker: p, base: base}
+}
+
+type pickerTransport struct {
+	picker *Picker
+	base   http.RoundTripper
+}
+
+func (t *pickerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint, done, err := t.picker.Pick()
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+
+	// RoundTripper 不能修改传入的请求
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	if target.Path != "" {
+		req.URL.Path = joinURLPath(target.Path, req.URL.Path)
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = joinURLPath(target.EscapedPath(), req.URL.RawPath)
+		}
+	}
+	req.Host = ""
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode >= 500 {
+		done(errors.New(resp.Status))
+	} else {
+		done(err)
+	}
+	return resp, err
+}
+
+// joinURLPath 拼接端点的路径前缀与请求路径，两者之间只保留一个 /
+func joinURLPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Run 每隔 ProbeInterval 重新探测一段时间没有结果的端点，直到 ctx 结束。
+// 慢的端点很少被选中，没有新的数据就没有机会证明自己已经恢复。
+func (p *Picker) Run(ctx context.Context) {
+	if p.ProbeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.ProbeStale(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ProbeStale 并发探测所有超过 ProbeInterval 没有结果的端点并等待完成，每次探测最长 ProbeTimeout
+func (p *Picker) ProbeStale(ctx context.Context) {
+	p.mu.Lock()
+	now := p.now()
+	var stale []string
+	for _, e := range p.order {
+		if now.Sub(p.stats[e].LastSeen) >= p.ProbeInterval {
+			stale = append(stale, e)
+		}
+	}
+	p.mu.Unlock()
+
+	probe := p.Probe
+	if probe == nil {
+		probe = func(ctx context.Context, endpoint string) error {
+			_, err := (&Hedger{}).ping(ctx, endpoint)
+			return err
+		}
+	}
+	var wg sync.WaitGroup
+	for _, e := range stale {
+		wg.Add(1)
+		go func(e string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, p.probeTimeout())
+			defer cancel()
+			start := p.now()
+			err := probe(ctx, e)
+			p.Observe(e, p.now().Sub(start), err)
+		}(e)
+	}
+	wg.Wait()
+}
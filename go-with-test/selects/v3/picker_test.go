@@ -0,0 +1,328 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPickerObserve(t *testing.T) {
+	p := NewPicker("a", "b")
+	p.Alpha = 0.5
+
+	p.Observe("a", 100*time.Millisecond, nil)
+	p.Observe("a", 200*time.Millisecond, nil)
+	// 被取消的请求只提供延迟下限
+	p.Observe("a", 10*time.Millisecond, context.Canceled)
+	p.Observe("unknown", time.Second, nil)
+
+	s := statsFor(p, "a")
+	if s.Latency != 150*time.Millisecond {
+		t.Errorf("Latency = %v, want 150ms", s.Latency)
+	}
+	if s.Requests != 2 {
+		t.Errorf("Requests = %d, want 2", s.Requests)
+	}
+
+	p.Observe("a", 350*time.Millisecond, context.Canceled)
+	if s := statsFor(p, "a"); s.Latency != 250*time.Millisecond || s.ErrorRate != 0 {
+		t.Errorf("a cancelled request slower than average raises the latency only: %+v", s)
+	}
+
+	// 很快返回的失败按 FailurePenalty 计入
+	p.Observe("a", 10*time.Millisecond, errors.New("boom"))
+	s = statsFor(p, "a")
+	if s.Latency != 625*time.Millisecond {
+		t.Errorf("Latency = %v, want 625ms", s.Latency)
+	}
+	if s.ErrorRate != 0.5 {
+		t.Errorf("ErrorRate = %v, want 0.5", s.ErrorRate)
+	}
+	if s.Requests != 4 {
+		t.Errorf("Requests = %d, want 4", s.Requests)
+	}
+}
+
+func TestPickerFastFailure(t *testing.T) {
+	p := NewPicker("dead", "healthy")
+	p.FailurePenalty = 100 * time.Millisecond
+	// 第一个样本就是失败，之后也一直失败，延迟仍然不会低于惩罚值
+	for i := 0; i < 10; i++ {
+		p.Observe("dead", time.Millisecond, errors.New("connection refused"))
+		p.Observe("healthy", 50*time.Millisecond, nil)
+	}
+	if s := statsFor(p, "dead"); s.Latency < p.FailurePenalty {
+		t.Errorf("failures lowered the latency: %+v", s)
+	}
+
+	for i := 0; i < 100; i++ {
+		e, _, err := p.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e != "healthy" {
+			t.Fatalf("picked %s over a healthy endpoint", e)
+		}
+		p.mu.Lock()
+		p.stats[e].InFlight--
+		p.mu.Unlock()
+	}
+
+	// 失败不会拉低已经很高的延迟
+	p.Observe("healthy", 2*time.Second, nil)
+	before := statsFor(p, "healthy").Latency
+	p.Observe("healthy", time.Millisecond, errors.New("503"))
+	if s := statsFor(p, "healthy"); s.Latency < before {
+		t.Errorf("Latency dropped from %v to %v after a failure", before, s.Latency)
+	}
+}
+
+func TestPickerPick(t *testing.T) {
+	p := NewPicker("fast", "slow", "flaky")
+	for i := 0; i < 10; i++ {
+		p.Observe("fast", 10*time.Millisecond, nil)
+		p.Observe("slow", 200*time.Millisecond, nil)
+		p.Observe("flaky", 10*time.Millisecond, errors.New("503"))
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		e, _, err := p.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[e]++
+		// 不记录结果，只释放并发计数
+		p.mu.Lock()
+		p.stats[e].InFlight--
+		p.mu.Unlock()
+	}
+	// 两两比较时 fast 总是胜出；flaky 虽然返回得快，但失败按 FailurePenalty 计入，
+	// 得分约 10s，是最差的，永远不会被选中，只能依靠 ProbeStale 重新探测
+	if counts["fast"] < 1800 || counts["slow"] < 800 || counts["flaky"] != 0 {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+
+	if got := p.Stats()[0].URL; got != "fast" {
+		t.Errorf("best endpoint = %s", got)
+	}
+	if _, _, err := NewPicker().Pick(); err != NoEndpointsError {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestPickerInFlight(t *testing.T) {
+	p := NewPicker("a", "b")
+	p.Observe("a", 10*time.Millisecond, nil)
+	p.Observe("b", 25*time.Millisecond, nil)
+
+	// a 更快，但有两个请求在进行时 b 的得分更好
+	var dones []func(error)
+	for len(dones) < 2 {
+		e, done, _ := p.Pick()
+		if e == "a" {
+			dones = append(dones, done)
+		} else {
+			p.mu.Lock()
+			p.stats[e].InFlight--
+			p.mu.Unlock()
+		}
+	}
+	if e, _, _ := p.Pick(); e != "b" {
+		t.Errorf("got %s, want b while a is busy", e)
+	}
+	for _, done := range dones {
+		done(nil)
+		done(nil)
+	}
+	if s := statsFor(p, "a"); s.InFlight != 0 || s.Requests != 3 {
+		t.Errorf("done should be idempotent: %+v", s)
+	}
+}
+
+func TestPickerObserveRace(t *testing.T) {
+	fast, _, _ := makeStatusServer(0, http.StatusOK)
+	defer fast.Close()
+	slow, _, _ := makeStatusServer(time.Second, http.StatusOK)
+	defer slow.Close()
+
+	p := NewPicker(fast.URL, slow.URL, "http://never-launched")
+	h := &Hedger{Delay: 0}
+	res, err := h.Race(context.Background(), fast.URL, slow.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ObserveRace(res)
+	res.Candidates = append(res.Candidates, Candidate{URL: "http://never-launched"})
+	p.ObserveRace(res)
+
+	if s := statsFor(p, fast.URL); s.Requests != 2 || s.ErrorRate != 0 {
+		t.Errorf("winner: %+v", s)
+	}
+	if s := statsFor(p, slow.URL); s.Requests == 0 || s.ErrorRate != 0 {
+		t.Errorf("cancelled loser should not count as an error: %+v", s)
+	}
+	if s := statsFor(p, "http://never-launched"); s.Requests != 0 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestPickerTransport(t *testing.T) {
+	var mu sync.Mutex
+	hosts := map[string]int{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts[r.Host]++
+		mu.Unlock()
+		if r.URL.Path != "/api/players" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	a := httptest.NewServer(handler)
+	defer a.Close()
+	b := httptest.NewServer(handler)
+	defer b.Close()
+
+	p := NewPicker(a.URL, b.URL)
+	client := &http.Client{Transport: p.Transport(nil)}
+	for i := 0; i < 20; i++ {
+		resp, err := client.Get("http://players.internal/api/players")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got %d", resp.StatusCode)
+		}
+	}
+	if len(hosts) != 2 {
+		t.Errorf("requests should be spread over both mirrors: %v", hosts)
+	}
+	total := 0
+	for _, s := range p.Stats() {
+		total += s.Requests
+	}
+	if total != 20 {
+		t.Errorf("recorded %d requests, want 20", total)
+	}
+
+	t.Run("path prefix", func(t *testing.T) {
+		var got string
+		mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.URL.EscapedPath()
+		}))
+		defer mirror.Close()
+
+		client := &http.Client{Transport: NewPicker(mirror.URL + "/v2").Transport(nil)}
+		resp, err := client.Get("http://players.internal/files/a%2Fb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got != "/v2/files/a%2Fb" {
+			t.Errorf("got path %q", got)
+		}
+	})
+
+	t.Run("Do", func(t *testing.T) {
+		var got string
+		err := p.Do(context.Background(), func(ctx context.Context, endpoint string) error {
+			got = endpoint
+			return errors.New("unavailable")
+		})
+		if err == nil || statsFor(p, got).ErrorRate == 0 {
+			t.Errorf("error should be recorded for %s", got)
+		}
+	})
+}
+
+// 把 Picker 接入 http.Client：请求的 host 只是占位，实际发往选中的镜像，镜像的路径前缀会被保留
+func ExamplePicker_Transport() {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "served %s", r.URL.Path)
+	}))
+	defer mirror.Close()
+
+	picker := NewPicker(mirror.URL + "/mirror/")
+	client := &http.Client{Transport: picker.Transport(nil)}
+	resp, err := client.Get("http://players.internal/api/players")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Println(string(body))
+	fmt.Println(picker.Stats()[0].Requests)
+	// Output:
+	// served /mirror/api/players
+	// 1
+}
+
+func TestPickerProbeStale(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	var probed []string
+	p := NewPicker("a", "b")
+	p.ProbeInterval = time.Minute
+	p.Now = func() time.Time { return now }
+	p.Probe = func(ctx context.Context, endpoint string) error {
+		mu.Lock()
+		probed = append(probed, endpoint)
+		mu.Unlock()
+		return nil
+	}
+
+	p.Observe("a", 500*time.Millisecond, nil)
+	p.Observe("b", 10*time.Millisecond, nil)
+	now = now.Add(30 * time.Second)
+	p.Observe("b", 10*time.Millisecond, nil)
+
+	now = now.Add(40 * time.Second)
+	p.ProbeStale(context.Background())
+	if len(probed) != 1 || probed[0] != "a" {
+		t.Errorf("probed %v, want only the stale endpoint a", probed)
+	}
+	if s := statsFor(p, "a"); s.Requests != 2 || s.Latency >= 500*time.Millisecond {
+		t.Errorf("probe result should be recorded: %+v", s)
+	}
+}
+
+func TestPickerProbeTimeout(t *testing.T) {
+	p := NewPicker("hung")
+	p.ProbeInterval = time.Minute
+	p.ProbeTimeout = 20 * time.Millisecond
+	p.Probe = func(ctx context.Context, endpoint string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		p.ProbeStale(context.Background())
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ProbeStale did not give up on a hung endpoint")
+	}
+	if s := statsFor(p, "hung"); s.Requests != 1 || s.ErrorRate == 0 {
+		t.Errorf("a timed out probe should count as a failure: %+v", s)
+	}
+}
+
+func statsFor(p *Picker, endpoint string) EndpointStats {
+	for _, s := range p.Stats() {
+		if s.URL == endpoint {
+			return s
+		}
+	}
+	return EndpointStats{}
+}
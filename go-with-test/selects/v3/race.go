@@ -0,0 +1,270 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var NoCandidatesError = errors.New("no candidates to race")
+
+// Candidate 参赛 URL 的结果
+type Candidate struct {
+	URL string
+	// Launched 开启对冲时排在后面的 URL 可能还没发出请求比赛就结束了
+	Launched bool
+	// Start 相对比赛开始的时间，Latency 为请求本身的耗时
+	Start   time.Duration
+	Latency time.Duration
+	Status  int
+	Won     bool
+	// Err 请求失败或者状态码不算成功，输掉比赛而被取消的为 context.Canceled
+	Err error
+}
+
+// RaceResult 比赛结果，Candidates 与传入的 urls 顺序一致
+type RaceResult struct {
+	Winner     string
+	Elapsed    time.Duration
+	Candidates []Candidate
+}
+
+// Race 同时请求所有 URL，返回第一个成功响应的；其余请求会被取消，返回前所有请求都已结束
+func Race(ctx context.Context, urls ...string) (RaceResult, error) {
+	return (&Hedger{}).Race(ctx, urls...)
+}
+
+// Hedger 对冲请求：先只请求第一个 URL，超过 Delay 还没有成功才请求下一个，以少量额外请求换取更低的尾延迟。
+// 某个请求失败时立即请求下一个，不再等待。
+type Hedger struct {
+	// Client 为 nil 时使用 http.DefaultClient
+	Client *http.Client
+	// Delay 发出下一个请求前等待的时间，0 表示同时请求所有 URL
+	Delay time.Duration
+	// Tracker 不为 nil 时用它记录的成功延迟的 Percentile 分位数作为等待时间，样本不足时使用 Delay
+	Tracker    *LatencyTracker
+	Percentile float64
+	// Success 判断状态码是否算作成功，默认只接受 2xx
+	Success func(status int) bool
+}
+
+type attempt struct {
+	index  int
+	status int
+	err    error
+	end    time.Time
+}
+
+// Race 按 urls 的顺序依次对冲请求，返回第一个成功响应的 URL
+func (h *Hedger) Race(ctx context.Context, urls ...string) (RaceResult, error) {
+	if len(urls) == 0 {
+		return RaceResult{}, NoCandidatesError
+	}
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res := RaceResult{Candidates: make([]Candidate, len(urls))}
+	for i, u := range urls {
+		res.Candidates[i].URL = u
+	}
+
+	// 缓冲区足够所有请求写入，输掉的请求不会像 ping 那样永远阻塞
+	done := make(chan attempt, len(urls))
+	launched := 0
+	launch := func() {
+		i := launched
+		launched++
+		res.Candidates[i].Launched = true
+		res.Candidates[i].Start = time.Since(start)
+		go func() {
+			status, err := h.ping(raceCtx, urls[i])
+			done <- attempt{index: i, status: status, err: err, end: time.Now()}
+		}()
+	}
+
+	delay := h.delay()
+	var timer *time.Timer
+	var hedge <-chan time.Time
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		hedge = nil
+		if launched < len(urls) {
+			timer = time.NewTimer(delay)
+			hedge = timer.C
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	if delay <= 0 {
+		for launched < len(urls) {
+			launch()
+		}
+	} else {
+		launch()
+		schedule()
+	}
+
+	record := func(a attempt) {
+		c := &res.Candidates[a.index]
+		c.Status, c.Err = a.status, a.err
+		c.Latency = a.end.Sub(start) - c.Start
+	}
+
+	finished, winner := 0, -1
+	for winner < 0 && finished < launched && ctx.Err() == nil {
+		select {
+		case a := <-done:
+			finished++
+			record(a)
+			if a.err == nil {
+				winner = a.index
+			} else if launched < len(urls) {
+				launch()
+				schedule()
+			}
+		case <-hedge:
+			launch()
+			schedule()
+		case <-ctx.Done():
+		}
+	}
+
+	// 取消其余的请求并等待它们结束
+	cancel()
+	for ; finished < launched; finished++ {
+		a := <-done
+		record(a)
+		if winner >= 0 || ctx.Err() != nil {
+			res.Candidates[a.index].Err = context.Canceled
+		}
+	}
+	res.Elapsed = time.Since(start)
+
+	if winner < 0 {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		var msgs []string
+		for _, c := range res.Candidates {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", c.URL, c.Err))
+		}
+		return res, fmt.Errorf("all %d candidates failed: %s", len(urls), strings.Join(msgs, "; "))
+	}
+
+	w := &res.Candidates[winner]
+	w.Won = true
+	res.Winner = w.URL
+	if h.Tracker != nil {
+		h.Tracker.Observe(w.Latency)
+	}
+	return res, nil
+}
+
+func (h *Hedger) delay() time.Duration {
+	if h.Tracker != nil && h.Percentile > 0 {
+		if d, ok := h.Tracker.Percentile(h.Percentile); ok {
+			return d
+		}
+	}
+	return h.Delay
+}
+
+// maxDrainBytes 关闭响应前最多读取并丢弃的字节数
+const maxDrainBytes = 64 << 10
+
+func (h *Hedger) ping(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// 读完响应体，连接才能被复用；限制读取的长度，避免很大的响应拖住对冲
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+
+	success := h.Success
+	if success == nil {
+		success = func(status int) bool { return status >= 200 && status < 300 }
+	}
+	if !success(resp.StatusCode) {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// LatencyTracker 保存最近 size 次成功请求的延迟，用于计算对冲的等待时间
+type LatencyTracker struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	// minSamples 样本数少于它时 Percentile 不可用
+	minSamples int
+}
+
+// NewLatencyTracker 保存最近 size 个样本，至少 10 个样本后 Percentile 才可用
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size <= 0 {
+		size = 100
+	}
+	minSamples := 10
+	if minSamples > size {
+		minSamples = size
+	}
+	return &LatencyTracker{samples: make([]time.Duration, size), minSamples: minSamples}
+}
+
+// Observe 记录一次延迟
+func (t *LatencyTracker) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Percentile 返回 p 分位数 (0 < p <= 1)，样本不足时 ok 为 false
+func (t *LatencyTracker) Percentile(p float64) (d time.Duration, ok bool) {
+	t.mu.Lock()
+	n := t.next
+	if t.full {
+		n = len(t.samples)
+	}
+	sorted := append([]time.Duration(nil), t.samples[:n]...)
+	t.mu.Unlock()
+
+	if n == 0 || n < t.minSamples || p <= 0 {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p*float64(n)+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= n {
+		i = n - 1
+	}
+	return sorted[i], true
+}
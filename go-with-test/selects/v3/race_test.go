@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// makeStatusServer 延迟 duration 后返回 status，记录收到的请求数与被取消的请求数
+func makeStatusServer(duration time.Duration, status int) (srv *httptest.Server, hits, cancelled *int32) {
+	hits, cancelled = new(int32), new(int32)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		select {
+		case <-time.After(duration):
+			w.WriteHeader(status)
+		case <-r.Context().Done():
+			atomic.AddInt32(cancelled, 1)
+		}
+	}))
+	return srv, hits, cancelled
+}
+
+func TestRace(t *testing.T) {
+	slow, _, slowCancelled := makeStatusServer(500*time.Millisecond, http.StatusOK)
+	defer slow.Close()
+	fast, _, _ := makeStatusServer(10*time.Millisecond, http.StatusOK)
+	defer fast.Close()
+	broken, _, _ := makeStatusServer(0, http.StatusInternalServerError)
+	defer broken.Close()
+
+	res, err := Race(context.Background(), slow.URL, broken.URL, fast.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Winner != fast.URL {
+		t.Errorf("got winner %s, want %s (a 500 must not win)", res.Winner, fast.URL)
+	}
+	if res.Elapsed > 300*time.Millisecond {
+		t.Errorf("race took %v, losers should be cancelled", res.Elapsed)
+	}
+
+	c := res.Candidates
+	if !c[2].Won || c[2].Status != http.StatusOK || c[2].Latency <= 0 {
+		t.Errorf("winner: %+v", c[2])
+	}
+	if c[1].Status != http.StatusInternalServerError || c[1].Err == nil {
+		t.Errorf("broken: %+v", c[1])
+	}
+	if !errors.Is(c[0].Err, context.Canceled) {
+		t.Errorf("slow: got %v, want context.Canceled", c[0].Err)
+	}
+	// 服务端感知到取消可能稍有延迟
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(slowCancelled) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(slowCancelled) != 1 {
+		t.Error("losing request was not cancelled")
+	}
+
+	t.Run("all candidates fail", func(t *testing.T) {
+		res, err := Race(context.Background(), broken.URL, "http://127.0.0.1:1")
+		if err == nil || res.Winner != "" {
+			t.Fatalf("got %q, %v", res.Winner, err)
+		}
+		for _, c := range res.Candidates {
+			if c.Err == nil {
+				t.Errorf("%s: want an error", c.URL)
+			}
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := Race(ctx, slow.URL, slow.URL)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("no candidates", func(t *testing.T) {
+		if _, err := Race(context.Background()); err != NoCandidatesError {
+			t.Errorf("got %v", err)
+		}
+	})
+}
+
+func TestHedger(t *testing.T) {
+	t.Run("primary answers before the hedge delay", func(t *testing.T) {
+		primary, _, _ := makeStatusServer(5*time.Millisecond, http.StatusOK)
+		defer primary.Close()
+		backup, backupHits, _ := makeStatusServer(0, http.StatusOK)
+		defer backup.Close()
+
+		h := &Hedger{Delay: 200 * time.Millisecond}
+		res, err := h.Race(context.Background(), primary.URL, backup.URL)
+		if err != nil || res.Winner != primary.URL {
+			t.Fatalf("got %q, %v", res.Winner, err)
+		}
+		if atomic.LoadInt32(backupHits) != 0 || res.Candidates[1].Launched {
+			t.Error("backup should not be requested")
+		}
+	})
+
+	t.Run("slow primary is hedged", func(t *testing.T) {
+		primary, _, _ := makeStatusServer(time.Second, http.StatusOK)
+		defer primary.Close()
+		backup, _, _ := makeStatusServer(0, http.StatusOK)
+		defer backup.Close()
+
+		h := &Hedger{Delay: 30 * time.Millisecond}
+		res, err := h.Race(context.Background(), primary.URL, backup.URL)
+		if err != nil || res.Winner != backup.URL {
+			t.Fatalf("got %q, %v", res.Winner, err)
+		}
+		if start := res.Candidates[1].Start; start < 30*time.Millisecond {
+			t.Errorf("backup started after %v, want at least the hedge delay", start)
+		}
+	})
+
+	t.Run("failure launches the next candidate immediately", func(t *testing.T) {
+		broken, _, _ := makeStatusServer(0, http.StatusServiceUnavailable)
+		defer broken.Close()
+		backup, _, _ := makeStatusServer(0, http.StatusOK)
+		defer backup.Close()
+
+		h := &Hedger{Delay: time.Minute}
+		res, err := h.Race(context.Background(), broken.URL, backup.URL)
+		if err != nil || res.Winner != backup.URL {
+			t.Fatalf("got %q, %v", res.Winner, err)
+		}
+	})
+
+	t.Run("percentile delay", func(t *testing.T) {
+		tracker := NewLatencyTracker(20)
+		h := &Hedger{Delay: time.Minute, Tracker: tracker, Percentile: 0.9}
+		if d := h.delay(); d != time.Minute {
+			t.Errorf("without samples got %v, want the fixed delay", d)
+		}
+		for i := 1; i <= 10; i++ {
+			tracker.Observe(time.Duration(i) * time.Millisecond)
+		}
+		if d := h.delay(); d != 9*time.Millisecond {
+			t.Errorf("got p90 %v, want 9ms", d)
+		}
+
+		srv, _, _ := makeStatusServer(0, http.StatusOK)
+		defer srv.Close()
+		if _, err := h.Race(context.Background(), srv.URL); err != nil {
+			t.Fatal(err)
+		}
+		if tracker.next != 11 {
+			t.Errorf("winner latency should be observed, got %d samples", tracker.next)
+		}
+	})
+}
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := NewLatencyTracker(10)
+	for i := 1; i <= 25; i++ {
+		tracker.Observe(time.Duration(i))
+	}
+	// 只保留最近的 10 个样本 16..25
+	cases := map[float64]time.Duration{0.1: 16, 0.5: 20, 0.99: 25, 1: 25}
+	for p, want := range cases {
+		if got, ok := tracker.Percentile(p); !ok || got != want {
+			t.Errorf("p%v = %v, want %v", p*100, got, want)
+		}
+	}
+}
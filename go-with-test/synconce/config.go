@@ -1,10 +1,10 @@
 package synconce
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
-	"sync"
 )
 
 type Config struct {
@@ -12,26 +12,29 @@ type Config struct {
 	Port   int64
 }
 
-var (
-	once   sync.Once
-	config *Config
-)
+// config 第一次 ReadConfig 时从环境变量加载；加载失败不会被缓存，修正环境变量后再次调用即可
+var config = NewLazy(loadConfig)
 
-func ReadConfig() *Config {
-	once.Do(func() {
-		var err error
-		config = &Config{
-			Server: os.Getenv("SERVER"),
-		}
-		config.Port, err = strconv.ParseInt(os.Getenv("PORT"), 10, 0)
-		if err != nil {
-			config.Port = 8080
-		}
+// ReadConfig 返回全局配置，PORT 未设置时默认为 8080，设置了但不是合法端口时返回错误
+func ReadConfig() (*Config, error) {
+	return config.Get()
+}
 
-		log.Println("init config")
-	})
+func loadConfig() (*Config, error) {
+	cfg := &Config{
+		Server: os.Getenv("SERVER"),
+		Port:   8080,
+	}
+	if port, ok := os.LookupEnv("PORT"); ok {
+		p, err := strconv.ParseInt(port, 10, 0)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid PORT %q", port)
+		}
+		cfg.Port = p
+	}
 
-	return config
+	log.Println("init config")
+	return cfg, nil
 }
 
 //func main() {
@@ -1,6 +1,7 @@
 package synconce
 
 import (
+	"os"
 	"testing"
 	"time"
 )
@@ -8,8 +9,43 @@ import (
 func TestReadConfig(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		go func() {
-			_ = ReadConfig()
+			_, _ = ReadConfig()
 		}()
 	}
 	time.Sleep(time.Second)
 }
+
+func TestReadConfigPort(t *testing.T) {
+	t.Cleanup(config.Reset)
+
+	t.Run("default port", func(t *testing.T) {
+		config.Reset()
+		// t.Setenv 负责在测试结束后恢复原来的 PORT
+		t.Setenv("PORT", "")
+		os.Unsetenv("PORT")
+		cfg, err := ReadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Port != 8080 {
+			t.Errorf("got port %d, want 8080", cfg.Port)
+		}
+	})
+
+	t.Run("invalid port is not cached", func(t *testing.T) {
+		config.Reset()
+		t.Setenv("PORT", "http")
+		if _, err := ReadConfig(); err == nil {
+			t.Fatal("expected an error for PORT=http")
+		}
+
+		t.Setenv("PORT", "9090")
+		cfg, err := ReadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Port != 9090 {
+			t.Errorf("got port %d, want 9090", cfg.Port)
+		}
+	})
+}
@@ -0,0 +1,400 @@
+package synconce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ProviderExistError    = errors.New("provider already registered")
+	InvalidProviderError  = errors.New("invalid provider")
+	ContainerStartedError = errors.New("container already started")
+	NilInstanceError      = errors.New("provider returned nil")
+)
+
+// MissingDependencyError 某个构造函数依赖的类型没有注册
+type MissingDependencyError struct {
+	Type       reflect.Type
+	RequiredBy reflect.Type
+}
+
+func (e *MissingDependencyError) Error() string {
+	if e.RequiredBy == nil {
+		return fmt.Sprintf("no provider for %s", e.Type)
+	}
+	return fmt.Sprintf("no provider for %s (required by %s)", e.Type, e.RequiredBy)
+}
+
+// CycleError 构造函数之间存在循环依赖，Path 的首尾是同一个类型
+type CycleError struct {
+	Path []reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	names := make([]string, len(e.Path))
+	for i, t := range e.Path {
+		names[i] = t.String()
+	}
+	return "dependency cycle: " + strings.Join(names, " -> ")
+}
+
+// Starter 由需要在启动时执行初始化的组件实现，例如开始监听端口
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper 由需要优雅退出的组件实现。只实现了 io.Closer 的组件会在停止时调用 Close。
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// ContainerOption NewContainer 的可选项
+type ContainerOption func(*Container)
+
+// WithStartTimeout 单个组件 Start 的超时时间，默认 15s
+func WithStartTimeout(d time.Duration) ContainerOption {
+	return func(c *Container) {
+		c.startTimeout = d
+	}
+}
+
+// WithStopTimeout 单个组件 Stop 的超时时间，默认 15s
+func WithStopTimeout(d time.Duration) ContainerOption {
+	return func(c *Container) {
+		c.stopTimeout = d
+	}
+}
+
+// Container 依赖注入容器：按类型注册构造函数，构造函数的参数即其依赖。
+// 每个类型只会构造一次（基于 Lazy），构造失败时下一次 Resolve 会重试。
+//
+//	c := NewContainer()
+//	c.Provide(func() (*Config, error) { ... })
+//	c.Provide(func(cfg *Config) (PlayerStore, error) { ... })
+//	c.Provide(func(store PlayerStore) *PlayerServer { ... })
+//	if err := c.Start(ctx); err != nil { ... }
+//	defer c.Stop(context.Background())
+type Container struct {
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+
+	mu        sync.Mutex
+	providers map[reflect.Type]*provider
+	// ordered 按注册顺序排列，保证启动顺序是确定的
+	ordered []*provider
+	started []*provider
+	running bool
+}
+
+type provider struct {
+	typ  reflect.Type
+	ctor reflect.Value
+	deps []reflect.Type
+	lazy *Lazy[reflect.Value]
+	// validated 为 true 表示以它为根的依赖图已经检查过，由 c.mu 保护。
+	// 已注册的类型不能重复注册，后注册的组件不会改变已有组件的依赖，因此检查结果可以一直复用。
+	validated bool
+}
+
+// NewContainer 创建一个空的容器
+func NewContainer(opts ...ContainerOption) *Container {
+	c := &Container{
+		startTimeout: 15 * time.Second,
+		stopTimeout:  15 * time.Second,
+		providers:    map[reflect.Type]*provider{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Provide 注册构造函数，形如 func(deps...) T 或 func(deps...) (T, error)，注册的类型为 T。
+// 依赖是否存在、是否有环在 Resolve/Start 时检查，因此注册顺序无关紧要。
+func (c *Container) Provide(ctor any) error {
+	v := reflect.ValueOf(ctor)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("%w: %T is not a function", InvalidProviderError, ctor)
+	}
+	t := v.Type()
+	if t.IsVariadic() || t.NumOut() < 1 || t.NumOut() > 2 ||
+		(t.NumOut() == 2 && t.Out(1) != errorType) || t.Out(0) == errorType {
+		return fmt.Errorf("%w: %T, want func(deps...) T or func(deps...) (T, error)", InvalidProviderError, ctor)
+	}
+
+	p := &provider{typ: t.Out(0), ctor: v}
+	for i := 0; i < t.NumIn(); i++ {
+		p.deps = append(p.deps, t.In(i))
+	}
+	p.lazy = NewLazy(func() (reflect.Value, error) {
+		return c.construct(p)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return ContainerStartedError
+	}
+	if _, ok := c.providers[p.typ]; ok {
+		return fmt.Errorf("%w: %s", ProviderExistError, p.typ)
+	}
+	c.providers[p.typ] = p
+	c.ordered = append(c.ordered, p)
+	return nil
+}
+
+// Resolve 返回类型 T 的实例，必要时先构造其依赖
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	t := reflect.TypeFor[T]()
+	v, err := c.resolve(t, nil)
+	if err != nil {
+		return zero, err
+	}
+	// 接口类型的构造函数返回 nil 时 v.Interface() 为 nil，无法断言为 T
+	x, ok := v.Interface().(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", NilInstanceError, t)
+	}
+	return x, nil
+}
+
+// MustResolve 同 Resolve，失败时 panic
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Invoke 解析 fn 的所有参数后调用 fn，fn 可以返回一个 error
+func (c *Container) Invoke(fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("%w: Invoke expects a function, got %T", InvalidProviderError, fn)
+	}
+	t := v.Type()
+	if t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != errorType) {
+		return fmt.Errorf("%w: Invoke expects func(deps...) or func(deps...) error, got %T", InvalidProviderError, fn)
+	}
+	args := make([]reflect.Value, t.NumIn())
+	for i := range args {
+		arg, err := c.resolve(t.In(i), nil)
+		if err != nil {
+			return err
+		}
+		args[i] = arg
+	}
+	out := v.Call(args)
+	if len(out) == 1 && !out[0].IsNil() {
+		return out[0].Interface().(error)
+	}
+	return nil
+}
+
+// Validate 检查所有依赖是否都已注册、是否存在循环依赖，不会构造任何实例
+func (c *Container) Validate() error {
+	_, err := c.startOrder()
+	return err
+}
+
+func (c *Container) resolve(t reflect.Type, requiredBy reflect.Type) (reflect.Value, error) {
+	c.mu.Lock()
+	p, ok := c.providers[t]
+	c.mu.Unlock()
+	if !ok {
+		return reflect.Value{}, &MissingDependencyError{Type: t, RequiredBy: requiredBy}
+	}
+	// 先检查环，否则构造时会在 Lazy 的锁上死锁
+	if err := c.checkCycles(p); err != nil {
+		return reflect.Value{}, err
+	}
+	return p.lazy.Get()
+}
+
+func (c *Container) construct(p *provider) (reflect.Value, error) {
+	args := make([]reflect.Value, len(p.deps))
+	for i, dep := range p.deps {
+		arg, err := c.resolve(dep, p.typ)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+	out := p.ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("construct %s: %w", p.typ, out[1].Interface().(error))
+	}
+	return out[0], nil
+}
+
+// checkCycles 检查 root 的依赖图，每个组件只在第一次检查通过前做拓扑排序
+func (c *Container) checkCycles(root *provider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if root.validated {
+		return nil
+	}
+	_, err := c.topoSort([]*provider{root})
+	return err
+}
+
+// startOrder 返回所有组件的拓扑序：依赖排在使用者之前，无依赖关系的按注册顺序
+func (c *Container) startOrder() ([]*provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.topoSort(c.ordered)
+}
+
+// topoSort 深度优先遍历 roots 及其依赖，成功后把遍历到的组件标记为已检查，调用方需持有 c.mu
+func (c *Container) topoSort(roots []*provider) ([]*provider, error) {
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := map[*provider]int{}
+	var (
+		order []*provider
+		stack []reflect.Type
+	)
+
+	var visit func(p *provider) error
+	visit = func(p *provider) error {
+		switch state[p] {
+		case done:
+			return nil
+		case visiting:
+			// 从栈中第一次出现 p 的位置开始就是环
+			for i, t := range stack {
+				if t == p.typ {
+					path := append(append([]reflect.Type(nil), stack[i:]...), p.typ)
+					return &CycleError{Path: path}
+				}
+			}
+		}
+
+		state[p] = visiting
+		stack = append(stack, p.typ)
+		for _, dep := range p.deps {
+			dp, ok := c.providers[dep]
+			if !ok {
+				return &MissingDependencyError{Type: dep, RequiredBy: p.typ}
+			}
+			if err := visit(dp); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[p] = done
+		order = append(order, p)
+		return nil
+	}
+
+	for _, p := range roots {
+		if err := visit(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range order {
+		p.validated = true
+	}
+	return order, nil
+}
+
+// Start 检查依赖图后按拓扑序构造所有组件，并依次调用实现了 Starter 的组件的 Start。
+// 任何一个失败都会按相反的顺序停止已经启动的组件，然后返回错误。
+func (c *Container) Start(ctx context.Context) error {
+	order, err := c.startOrder()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return ContainerStartedError
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	for _, p := range order {
+		v, err := p.lazy.Get()
+		if err != nil {
+			return c.abortStart(err)
+		}
+		if s, ok := v.Interface().(Starter); ok {
+			if err := runHook(ctx, c.startTimeout, s.Start); err != nil {
+				return c.abortStart(fmt.Errorf("start %s: %w", p.typ, err))
+			}
+		}
+		c.mu.Lock()
+		c.started = append(c.started, p)
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *Container) abortStart(err error) error {
+	if stopErr := c.Stop(context.Background()); stopErr != nil {
+		return errors.Join(err, stopErr)
+	}
+	return err
+}
+
+// Stop 按启动的相反顺序停止组件，调用 Stopper.Stop 或 io.Closer.Close，返回所有停止失败的错误。
+// Stop 之后可以再次 Start，已经构造的实例会被复用。
+func (c *Container) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	started := c.started
+	c.started = nil
+	c.running = false
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		p := started[i]
+		v, err := p.lazy.Get()
+		if err != nil {
+			continue
+		}
+		var stop func(context.Context) error
+		switch s := v.Interface().(type) {
+		case Stopper:
+			stop = s.Stop
+		case io.Closer:
+			stop = func(context.Context) error { return s.Close() }
+		default:
+			continue
+		}
+		if err := runHook(ctx, c.stopTimeout, stop); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", p.typ, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runHook 在超时时间内执行生命周期钩子。钩子不响应 ctx 时也会按时返回，钩子本身在后台继续执行完。
+func runHook(ctx context.Context, timeout time.Duration, hook func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,306 @@
+package synconce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lifecycleLog 记录组件的启动与停止顺序
+type lifecycleLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *lifecycleLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *lifecycleLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.events, " ")
+}
+
+type testDB struct {
+	log      *lifecycleLog
+	startErr error
+}
+
+func (d *testDB) Start(ctx context.Context) error {
+	d.log.add("start:db")
+	return d.startErr
+}
+
+func (d *testDB) Stop(ctx context.Context) error {
+	d.log.add("stop:db")
+	return nil
+}
+
+type testRepo struct {
+	db *testDB
+}
+
+// testCache 只实现 io.Closer
+type testCache struct {
+	log *lifecycleLog
+}
+
+func (c *testCache) Close() error {
+	c.log.add("close:cache")
+	return nil
+}
+
+type testServer struct {
+	log   *lifecycleLog
+	repo  *testRepo
+	cache *testCache
+	block bool
+}
+
+func (s *testServer) Start(ctx context.Context) error {
+	s.log.add("start:server")
+	if s.block {
+		// 不响应 ctx 的钩子，依赖 runHook 的超时返回
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+func (s *testServer) Stop(ctx context.Context) error {
+	s.log.add("stop:server")
+	return nil
+}
+
+func newTestContainer(t *testing.T, log *lifecycleLog, opts ...ContainerOption) *Container {
+	t.Helper()
+	c := NewContainer(opts...)
+	providers := []any{
+		// 故意打乱注册顺序，启动顺序只取决于依赖关系
+		func(repo *testRepo, cache *testCache) *testServer {
+			return &testServer{log: log, repo: repo, cache: cache}
+		},
+		func(db *testDB) *testRepo { return &testRepo{db: db} },
+		func() *testCache { return &testCache{log: log} },
+		func() (*testDB, error) { return &testDB{log: log}, nil },
+	}
+	for _, p := range providers {
+		if err := c.Provide(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestContainerResolve(t *testing.T) {
+	log := &lifecycleLog{}
+	c := newTestContainer(t, log)
+
+	server, err := Resolve[*testServer](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.repo == nil || server.repo.db == nil || server.cache == nil {
+		t.Fatalf("dependencies not injected: %+v", server)
+	}
+	if again := MustResolve[*testServer](c); again != server {
+		t.Error("Resolve should return the same instance")
+	}
+	if db := MustResolve[*testDB](c); db != server.repo.db {
+		t.Error("dependencies should be shared")
+	}
+
+	var invoked bool
+	err = c.Invoke(func(s *testServer, db *testDB) error {
+		invoked = s == server && db == server.repo.db
+		return nil
+	})
+	if err != nil || !invoked {
+		t.Errorf("Invoke: err=%v invoked=%v", err, invoked)
+	}
+
+	_, err = Resolve[*strings.Builder](c)
+	var missing *MissingDependencyError
+	if !errors.As(err, &missing) {
+		t.Errorf("got %v, want MissingDependencyError", err)
+	}
+}
+
+func TestContainerProvide(t *testing.T) {
+	c := NewContainer()
+	invalid := []any{
+		nil,
+		42,
+		func() {},
+		func() error { return nil },
+		func() (int, int) { return 0, 0 },
+		func(...int) int { return 0 },
+	}
+	for _, p := range invalid {
+		if err := c.Provide(p); !errors.Is(err, InvalidProviderError) {
+			t.Errorf("Provide(%T) = %v, want InvalidProviderError", p, err)
+		}
+	}
+
+	if err := c.Provide(func() int { return 1 }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide(func() (int, error) { return 2, nil }); !errors.Is(err, ProviderExistError) {
+		t.Errorf("got %v, want ProviderExistError", err)
+	}
+}
+
+func TestContainerConstructError(t *testing.T) {
+	c := NewContainer()
+	fail := errors.New("connection refused")
+	attempts := 0
+	_ = c.Provide(func() (*testDB, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, fail
+		}
+		return &testDB{log: &lifecycleLog{}}, nil
+	})
+	_ = c.Provide(func(db *testDB) *testRepo { return &testRepo{db: db} })
+
+	if _, err := Resolve[*testRepo](c); !errors.Is(err, fail) {
+		t.Fatalf("got %v, want %v", err, fail)
+	}
+	// 与 Lazy 一样，失败的构造不会被缓存
+	if _, err := Resolve[*testRepo](c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContainerResolveNilInterface(t *testing.T) {
+	c := NewContainer()
+	_ = c.Provide(func() io.Closer { return nil })
+
+	if _, err := Resolve[io.Closer](c); !errors.Is(err, NilInstanceError) {
+		t.Errorf("got %v, want NilInstanceError", err)
+	}
+}
+
+func TestContainerValidatesOnce(t *testing.T) {
+	log := &lifecycleLog{}
+	c := newTestContainer(t, log)
+
+	if _, err := Resolve[*testRepo](c); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []reflect.Type{reflect.TypeFor[*testRepo](), reflect.TypeFor[*testDB]()} {
+		if !c.providers[typ].validated {
+			t.Errorf("%s should be marked as validated", typ)
+		}
+	}
+	if c.providers[reflect.TypeFor[*testServer]()].validated {
+		t.Error("providers outside the resolved graph should not be marked")
+	}
+
+	// 缺少依赖时不缓存，注册后可以正常解析
+	type later struct{}
+	_ = c.Provide(func(s fmt.Stringer) *later { return &later{} })
+	if _, err := Resolve[*later](c); err == nil {
+		t.Fatal("expected missing dependency")
+	}
+	_ = c.Provide(func() fmt.Stringer { return &strings.Builder{} })
+	if _, err := Resolve[*later](c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContainerCycle(t *testing.T) {
+	type a struct{}
+	type b struct{}
+	type c struct{}
+
+	ctr := NewContainer()
+	_ = ctr.Provide(func(*b) *a { return &a{} })
+	_ = ctr.Provide(func(*c) *b { return &b{} })
+	_ = ctr.Provide(func(*a) *c { return &c{} })
+
+	var cycle *CycleError
+	if err := ctr.Validate(); !errors.As(err, &cycle) {
+		t.Fatalf("got %v, want CycleError", err)
+	}
+	want := []reflect.Type{reflect.TypeFor[*a](), reflect.TypeFor[*b](), reflect.TypeFor[*c](), reflect.TypeFor[*a]()}
+	if !reflect.DeepEqual(cycle.Path, want) {
+		t.Errorf("got path %v, want %v", cycle.Path, want)
+	}
+	if !strings.Contains(cycle.Error(), " -> ") {
+		t.Errorf("unexpected message %q", cycle.Error())
+	}
+
+	if _, err := Resolve[*b](ctr); !errors.As(err, &cycle) {
+		t.Errorf("Resolve: got %v, want CycleError", err)
+	}
+	if err := ctr.Start(context.Background()); !errors.As(err, &cycle) {
+		t.Errorf("Start: got %v, want CycleError", err)
+	}
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	log := &lifecycleLog{}
+	c := newTestContainer(t, log)
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); !errors.Is(err, ContainerStartedError) {
+		t.Errorf("got %v, want ContainerStartedError", err)
+	}
+	if err := c.Provide(func() string { return "" }); !errors.Is(err, ContainerStartedError) {
+		t.Errorf("got %v, want ContainerStartedError", err)
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "start:db start:server stop:server close:cache stop:db"
+	if got := log.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestContainerStartFailure(t *testing.T) {
+	t.Run("stops started components", func(t *testing.T) {
+		log := &lifecycleLog{}
+		fail := errors.New("port in use")
+		c := NewContainer()
+		_ = c.Provide(func() *testCache { return &testCache{log: log} })
+		_ = c.Provide(func(*testCache) *testDB { return &testDB{log: log, startErr: fail} })
+		_ = c.Provide(func(db *testDB) *testServer { return &testServer{log: log} })
+
+		err := c.Start(context.Background())
+		if !errors.Is(err, fail) {
+			t.Fatalf("got %v, want %v", err, fail)
+		}
+		// testDB 启动失败，testServer 不会启动，已经启动的 testCache 被关闭
+		if got, want := log.String(), "start:db close:cache"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("start timeout", func(t *testing.T) {
+		log := &lifecycleLog{}
+		c := NewContainer(WithStartTimeout(50 * time.Millisecond))
+		_ = c.Provide(func() *testServer { return &testServer{log: log, block: true} })
+
+		begin := time.Now()
+		err := c.Start(context.Background())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+			t.Errorf("Start returned after %v, want about 50ms", elapsed)
+		}
+	})
+}
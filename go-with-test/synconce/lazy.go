@@ -0,0 +1,143 @@
+package synconce
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State Lazy 的初始化状态
+type State int32
+
+const (
+	// Uninitialized 还没有调用过 Get，或者刚被 Reset
+	Uninitialized State = iota
+	// Initializing 初始化函数正在执行
+	Initializing
+	// Ready 初始化成功，之后的 Get 直接返回缓存的值
+	Ready
+	// Failed 最近一次初始化失败，下一次 Get 会重试
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Uninitialized:
+		return "uninitialized"
+	case Initializing:
+		return "initializing"
+	case Ready:
+		return "ready"
+	case Failed:
+		return "failed"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// Status Lazy 的状态快照，用于健康检查
+type Status struct {
+	State State
+	// Attempts 自上次 Reset 以来调用初始化函数的次数
+	Attempts int
+	// LastError 最近一次初始化失败的错误，成功后清空
+	LastError error
+	// ReadyAt 初始化成功的时间
+	ReadyAt time.Time
+}
+
+// Lazy 延迟初始化的值。与 sync.Once 不同，初始化失败时不会缓存错误的结果，下一次 Get 会重新初始化；
+// 测试中可以调用 Reset 让它回到未初始化的状态。零值不可用，使用 NewLazy 创建。
+type Lazy[T any] struct {
+	init func() (T, error)
+
+	// mu 保证同一时刻只有一个初始化在执行
+	mu sync.Mutex
+	// value 初始化成功后不为 nil，Get 的快速路径只需要一次原子读
+	value atomic.Pointer[T]
+	state atomic.Int32
+
+	statusMu  sync.Mutex
+	attempts  int
+	lastError error
+	readyAt   time.Time
+}
+
+// NewLazy 创建一个 Lazy，init 在第一次 Get 时才会被调用
+func NewLazy[T any](init func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{init: init}
+}
+
+// Get 返回初始化后的值。初始化失败时返回错误，之后的调用会再次尝试初始化。
+// 并发调用时只有一个 goroutine 执行初始化，其余的等待其结果。
+func (l *Lazy[T]) Get() (T, error) {
+	if v := l.value.Load(); v != nil {
+		return *v, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 等锁期间其它 goroutine 可能已经初始化成功
+	if v := l.value.Load(); v != nil {
+		return *v, nil
+	}
+
+	l.state.Store(int32(Initializing))
+	v, err := l.init()
+
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+	l.attempts++
+	if err != nil {
+		l.lastError = err
+		l.state.Store(int32(Failed))
+		var zero T
+		return zero, err
+	}
+	l.lastError = nil
+	l.readyAt = time.Now()
+	l.value.Store(&v)
+	l.state.Store(int32(Ready))
+	return v, nil
+}
+
+// MustGet 同 Get，初始化失败时 panic，适合在程序启动阶段使用
+func (l *Lazy[T]) MustGet() T {
+	v, err := l.Get()
+	if err != nil {
+		panic(fmt.Sprintf("synconce: lazy init failed: %v", err))
+	}
+	return v
+}
+
+// Reset 丢弃已经初始化的值，下一次 Get 会重新初始化。正在进行的初始化会先执行完。
+func (l *Lazy[T]) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.value.Store(nil)
+	l.state.Store(int32(Uninitialized))
+
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+	l.attempts = 0
+	l.lastError = nil
+	l.readyAt = time.Time{}
+}
+
+// State 返回当前状态，不会阻塞
+func (l *Lazy[T]) State() State {
+	return State(l.state.Load())
+}
+
+// Status 返回状态快照，不会阻塞在正在进行的初始化上
+func (l *Lazy[T]) Status() Status {
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+	return Status{
+		State:     l.State(),
+		Attempts:  l.attempts,
+		LastError: l.lastError,
+		ReadyAt:   l.readyAt,
+	}
+}
@@ -0,0 +1,111 @@
+package synconce
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	t.Run("initializes once under concurrency", func(t *testing.T) {
+		var calls atomic.Int32
+		lazy := NewLazy(func() (int, error) {
+			calls.Add(1)
+			return 42, nil
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v := lazy.MustGet(); v != 42 {
+					t.Errorf("got %d, want 42", v)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if got := calls.Load(); got != 1 {
+			t.Errorf("init called %d times, want 1", got)
+		}
+		if got := lazy.State(); got != Ready {
+			t.Errorf("got state %s, want %s", got, Ready)
+		}
+	})
+
+	t.Run("retries after failure", func(t *testing.T) {
+		boom := errors.New("boom")
+		fail := true
+		lazy := NewLazy(func() (string, error) {
+			if fail {
+				return "bad", boom
+			}
+			return "good", nil
+		})
+
+		v, err := lazy.Get()
+		if !errors.Is(err, boom) || v != "" {
+			t.Fatalf("got (%q, %v), want zero value and %v", v, err, boom)
+		}
+		status := lazy.Status()
+		if status.State != Failed || status.Attempts != 1 || status.LastError != boom {
+			t.Errorf("unexpected status after failure: %+v", status)
+		}
+
+		fail = false
+		if v, err := lazy.Get(); err != nil || v != "good" {
+			t.Fatalf("got (%q, %v), want good", v, err)
+		}
+		status = lazy.Status()
+		if status.State != Ready || status.Attempts != 2 || status.LastError != nil || status.ReadyAt.IsZero() {
+			t.Errorf("unexpected status after success: %+v", status)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		n := 0
+		lazy := NewLazy(func() (int, error) {
+			n++
+			return n, nil
+		})
+
+		if v := lazy.MustGet(); v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+		lazy.Reset()
+		if got := lazy.Status(); got.State != Uninitialized || got.Attempts != 0 {
+			t.Errorf("unexpected status after reset: %+v", got)
+		}
+		if v := lazy.MustGet(); v != 2 {
+			t.Errorf("got %d after reset, want 2", v)
+		}
+	})
+
+	t.Run("state while initializing", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		lazy := NewLazy(func() (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+
+		go lazy.Get()
+		<-started
+		if got := lazy.Status().State; got != Initializing {
+			t.Errorf("got state %s, want %s", got, Initializing)
+		}
+		close(release)
+		lazy.MustGet()
+	})
+
+	t.Run("MustGet panics on error", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		NewLazy(func() (int, error) { return 0, errors.New("boom") }).MustGet()
+	})
+}
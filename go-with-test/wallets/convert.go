@@ -0,0 +1,111 @@
+package wallets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var RateNotFoundError = errors.New("exchange rate not found")
+
+// RateProvider 汇率来源，返回 1 个完整单位的 from 可以兑换多少个完整单位的 to
+type RateProvider interface {
+	Rate(from, to Currency) (*big.Rat, error)
+}
+
+// StaticRateProvider 固定汇率表，键为 "BTC/USD" 形式的币种对。
+// 只配置了一个方向时自动使用倒数作为反向汇率。
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider 从 "BTC/USD" -> "65000.50" 形式的映射构造汇率表，汇率使用十进制字符串以避免浮点误差
+func NewStaticRateProvider(rates map[string]string) (*StaticRateProvider, error) {
+	p := &StaticRateProvider{rates: map[string]*big.Rat{}}
+	for pair, value := range rates {
+		codes := strings.Split(pair, "/")
+		if len(codes) != 2 || codes[0] == "" || codes[1] == "" {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, pair)
+		}
+		p.rates[strings.ToUpper(strings.TrimSpace(codes[0]))+"/"+strings.ToUpper(strings.TrimSpace(codes[1]))] = rate
+	}
+	return p, nil
+}
+
+// LoadStaticRates 从 JSON 文件加载汇率表，例如 {"BTC/USD": "65000.50"}
+func LoadStaticRates(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rates file %s: %w", path, err)
+	}
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("parse rates file %s: %w", path, err)
+	}
+	return NewStaticRateProvider(rates)
+}
+
+// Rate 币种代码不区分大小写
+func (p *StaticRateProvider) Rate(from, to Currency) (*big.Rat, error) {
+	fromCode, toCode := strings.ToUpper(from.Code), strings.ToUpper(to.Code)
+	if fromCode == toCode {
+		return big.NewRat(1, 1), nil
+	}
+	if rate, ok := p.rates[fromCode+"/"+toCode]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := p.rates[toCode+"/"+fromCode]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, fmt.Errorf("%s/%s: %w", from, to, RateNotFoundError)
+}
+
+// Convert 将金额换算为目标币种，按目标币种的最小单位四舍五入（远离零）
+func Convert(m Money, to Currency, provider RateProvider) (Money, error) {
+	rate, err := provider.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// units_to = units_from / 10^from.Decimals * rate * 10^to.Decimals
+	value := new(big.Rat).SetInt64(m.Units)
+	value.Mul(value, rate)
+	value.Mul(value, new(big.Rat).SetInt64(to.unit()))
+	value.Quo(value, new(big.Rat).SetInt64(m.Currency.unit()))
+
+	units, err := roundRat(value)
+	if err != nil {
+		return Money{}, fmt.Errorf("convert %s to %s: %w", m, to, err)
+	}
+	return NewMoney(units, to), nil
+}
+
+// BalanceIn 以指定币种返回钱包余额
+func (w *Wallet) BalanceIn(currency Currency, provider RateProvider) (Money, error) {
+	return Convert(w.Balance().Satoshis(), currency, provider)
+}
+
+func roundRat(r *big.Rat) (int64, error) {
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	// 四舍五入：|num| * 2 + den 后整除 2*den，再补回符号
+	neg := num.Sign() < 0
+	num.Abs(num)
+	num.Mul(num, big.NewInt(2))
+	num.Add(num, den)
+	num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+	if neg {
+		num.Neg(num)
+	}
+	if !num.IsInt64() || num.Int64() == math.MinInt64 {
+		return 0, AmountOverflowError
+	}
+	return num.Int64(), nil
+}
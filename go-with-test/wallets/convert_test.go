@@ -0,0 +1,84 @@
+package wallets
+
+import (
+	"errors"
+	"testing"
+)
+
+func loadTestRates(t *testing.T) *StaticRateProvider {
+	t.Helper()
+	rates, err := LoadStaticRates("testdata/rates.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rates
+}
+
+func TestConvert(t *testing.T) {
+	rates := loadTestRates(t)
+
+	cases := []struct {
+		name  string
+		money Money
+		to    Currency
+		want  string
+	}{
+		{"direct", Bitcoin(2).Satoshis(), USD, "130001.00 USD"},
+		{"satoshi precision", NewMoney(1, BTC), USD, "0.00 USD"},
+		{"rounds half away from zero", NewMoney(1000, BTC), USD, "0.65 USD"},
+		{"inverse", NewMoney(6500050, USD), BTC, "1.00000000 BTC"},
+		{"same currency", NewMoney(42, CNY), CNY, "0.42 CNY"},
+		{"negative", NewMoney(-100, USD), CNY, "-7.12 CNY"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Convert(c.money, c.to, rates)
+			assertNoError(t, err)
+			if got.String() != c.want {
+				t.Errorf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+
+	t.Run("missing rate", func(t *testing.T) {
+		_, err := Convert(Bitcoin(1).Satoshis(), Currency{Code: "EUR", Decimals: 2}, rates)
+		if !errors.Is(err, RateNotFoundError) {
+			t.Errorf("got %v, want %v", err, RateNotFoundError)
+		}
+	})
+}
+
+func TestBalanceIn(t *testing.T) {
+	wallet := Wallet{balance: 3}
+
+	got, err := wallet.BalanceIn(USD, loadTestRates(t))
+	assertNoError(t, err)
+	if got.String() != "195001.50 USD" {
+		t.Errorf("got %s, want %s", got, "195001.50 USD")
+	}
+}
+
+func TestStaticRateProviderCase(t *testing.T) {
+	rates, err := NewStaticRateProvider(map[string]string{"btc/Usd": "2"})
+	assertNoError(t, err)
+	for _, pair := range [][2]Currency{{BTC, USD}, {{Code: "btc", Decimals: 8}, {Code: "usd", Decimals: 2}}} {
+		rate, err := rates.Rate(pair[0], pair[1])
+		assertNoError(t, err)
+		if rate.String() != "2/1" {
+			t.Errorf("%s/%s: got %s", pair[0].Code, pair[1].Code, rate)
+		}
+	}
+}
+
+func TestStaticRateProviderValidation(t *testing.T) {
+	for _, rates := range []map[string]string{
+		{"BTCUSD": "1"},
+		{"BTC/USD": "abc"},
+		{"BTC/USD": "-1"},
+	} {
+		if _, err := NewStaticRateProvider(rates); err == nil {
+			t.Errorf("expected an error for %v", rates)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package wallets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSystemWalletStore 将所有钱包的余额和流水以 JSON 保存在一个文件中，资金操作通过 Wallet 完成，流水与内存实现一致。
+// 每次变更先写临时文件再 rename 覆盖，落盘失败时回滚内存中的变更，保证进程崩溃时文件要么是旧值要么是新值。
+type FileSystemWalletStore struct {
+	mu      sync.Mutex
+	path    string
+	wallets map[string]*Wallet
+}
+
+// walletRecord 文件中一个钱包的内容
+type walletRecord struct {
+	Balance Bitcoin       `json:"balance"`
+	History []Transaction `json:"history,omitempty"`
+}
+
+func FileSystemWalletStoreFromFile(path string) (*FileSystemWalletStore, error) {
+	store := &FileSystemWalletStore{path: path, wallets: map[string]*Wallet{}}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return store, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to open wallet store file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("problem loading wallet store from file %s: %w", path, err)
+	}
+	for id, value := range raw {
+		var record walletRecord
+		// 旧版本的文件只保存余额，形如 {"alice": 15}
+		if err := json.Unmarshal(value, &record.Balance); err != nil {
+			if err := json.Unmarshal(value, &record); err != nil {
+				return nil, fmt.Errorf("problem loading wallet %q from file %s: %w", id, path, err)
+			}
+		}
+		store.wallets[id] = &Wallet{balance: record.Balance, history: record.History}
+	}
+	return store, nil
+}
+
+func (f *FileSystemWalletStore) Balance(id string) (Bitcoin, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w, err := f.wallet(id)
+	if err != nil {
+		return 0, err
+	}
+	return w.Balance(), nil
+}
+
+func (f *FileSystemWalletStore) Statement(id string, from, to time.Time) (Statement, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w, err := f.wallet(id)
+	if err != nil {
+		return Statement{}, err
+	}
+	return w.Statement(from, to), nil
+}
+
+func (f *FileSystemWalletStore) Deposit(id string, amount Bitcoin) (Bitcoin, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("deposit %s: %w", amount, InvalidAmountError)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w, ok := f.wallets[id]
+	if !ok {
+		w = &Wallet{}
+		f.wallets[id] = w
+	}
+	if err := f.apply(func() error { return w.DepositWithMemo(amount, "") }, w); err != nil {
+		if !ok {
+			delete(f.wallets, id)
+		}
+		return 0, err
+	}
+	return w.Balance(), nil
+}
+
+func (f *FileSystemWalletStore) Withdraw(id string, amount Bitcoin) (Bitcoin, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("withdraw %s: %w", amount, InvalidAmountError)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w, err := f.wallet(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.apply(func() error { return w.WithdrawWithMemo(amount, "") }, w); err != nil {
+		return w.Balance(), err
+	}
+	return w.Balance(), nil
+}
+
+func (f *FileSystemWalletStore) Transfer(from, to string, amount Bitcoin) error {
+	if amount <= 0 {
+		return fmt.Errorf("transfer %s: %w", amount, InvalidAmountError)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	src, err := f.wallet(from)
+	if err != nil {
+		return err
+	}
+	dst, ok := f.wallets[to]
+	if !ok {
+		dst = &Wallet{}
+		f.wallets[to] = dst
+	}
+	err = f.apply(func() error { return Transfer(src, dst, amount) }, src, dst)
+	if err != nil && !ok {
+		delete(f.wallets, to)
+	}
+	return err
+}
+
+// apply 执行 op 并落盘，op 失败或落盘失败时把 wallets 恢复到执行前的状态，调用方需持有 f.mu。
+// 新建的钱包由调用方在 apply 之前放入 f.wallets，以便一起落盘。
+func (f *FileSystemWalletStore) apply(op func() error, wallets ...*Wallet) error {
+	type snapshot struct {
+		balance Bitcoin
+		history int
+	}
+	before := make([]snapshot, len(wallets))
+	for i, w := range wallets {
+		w.mu.Lock()
+		before[i] = snapshot{w.balance, len(w.history)}
+		w.mu.Unlock()
+	}
+	restore := func() {
+		for i, w := range wallets {
+			w.mu.Lock()
+			w.balance, w.history = before[i].balance, w.history[:before[i].history]
+			w.mu.Unlock()
+		}
+	}
+
+	if err := op(); err != nil {
+		return err
+	}
+	if err := f.save(); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+func (f *FileSystemWalletStore) wallet(id string) (*Wallet, error) {
+	w, ok := f.wallets[id]
+	if !ok {
+		return nil, fmt.Errorf("wallet %q: %w", id, WalletNotFoundError)
+	}
+	return w, nil
+}
+
+// save 将所有钱包整体落盘，调用方需持有 f.mu
+func (f *FileSystemWalletStore) save() error {
+	records := make(map[string]walletRecord, len(f.wallets))
+	for id, w := range f.wallets {
+		w.mu.Lock()
+		records[id] = walletRecord{Balance: w.balance, History: append([]Transaction(nil), w.history...)}
+		w.mu.Unlock()
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save wallet store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save wallet store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save wallet store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save wallet store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to save wallet store: %w", err)
+	}
+	return nil
+}
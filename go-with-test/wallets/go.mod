@@ -1 +1,3 @@
 module wallets
+
+go 1.27.1
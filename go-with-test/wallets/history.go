@@ -0,0 +1,119 @@
+package wallets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TransactionKind 流水类型
+type TransactionKind string
+
+const (
+	DepositTransaction     TransactionKind = "deposit"
+	WithdrawTransaction    TransactionKind = "withdraw"
+	TransferInTransaction  TransactionKind = "transfer-in"
+	TransferOutTransaction TransactionKind = "transfer-out"
+)
+
+// Transaction 一条余额变动流水，Amount 入账为正、出账为负，Balance 为变动后的余额
+type Transaction struct {
+	Time    time.Time       `json:"time"`
+	Kind    TransactionKind `json:"kind"`
+	Amount  Bitcoin         `json:"amount"`
+	Memo    string          `json:"memo,omitempty"`
+	Balance Bitcoin         `json:"balance"`
+}
+
+// record 追加流水，调用方需持有 w.mu
+func (w *Wallet) record(kind TransactionKind, amount Bitcoin, memo string) {
+	now := time.Now
+	if w.now != nil {
+		now = w.now
+	}
+	w.history = append(w.history, Transaction{
+		Time:    now(),
+		Kind:    kind,
+		Amount:  amount,
+		Memo:    memo,
+		Balance: w.balance,
+	})
+}
+
+// History 返回全部流水的副本，按时间先后排列
+func (w *Wallet) History() []Transaction {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Transaction(nil), w.history...)
+}
+
+// Statement 对账单，覆盖 [From, To) 区间
+type Statement struct {
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	OpeningBalance Bitcoin       `json:"opening_balance"`
+	ClosingBalance Bitcoin       `json:"closing_balance"`
+	Transactions   []Transaction `json:"transactions"`
+}
+
+// Statement 生成 [from, to) 区间的对账单。
+// 期初余额由流水倒推，因此即使钱包创建时就带有余额（没有对应流水）也能对平。
+func (w *Wallet) Statement(from, to time.Time) Statement {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	balance := w.balance
+	for _, tx := range w.history {
+		balance -= tx.Amount
+	}
+
+	s := Statement{From: from, To: to, Transactions: []Transaction{}}
+	for _, tx := range w.history {
+		if !tx.Time.Before(from) {
+			break
+		}
+		balance = tx.Balance
+	}
+	s.OpeningBalance = balance
+
+	for _, tx := range w.history {
+		if tx.Time.Before(from) || !tx.Time.Before(to) {
+			continue
+		}
+		s.Transactions = append(s.Transactions, tx)
+		balance = tx.Balance
+	}
+	s.ClosingBalance = balance
+	return s
+}
+
+// WriteJSON 以 JSON 格式导出对账单
+func (s Statement) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV 以 CSV 格式导出对账单中的流水，金额为整数 BTC
+func (s Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "kind", "amount", "memo", "balance"}); err != nil {
+		return err
+	}
+	for _, tx := range s.Transactions {
+		record := []string{
+			tx.Time.Format(time.RFC3339),
+			string(tx.Kind),
+			strconv.Itoa(int(tx.Amount)),
+			tx.Memo,
+			strconv.Itoa(int(tx.Balance)),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,117 @@
+package wallets
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeClock 每次调用前进一小时
+func fakeClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		t := now
+		now = now.Add(time.Hour)
+		return t
+	}
+}
+
+var day = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newHistoryWallet(t *testing.T) *Wallet {
+	t.Helper()
+	wallet := &Wallet{balance: 5, now: fakeClock(day)}
+	wallet.DepositWithMemo(100, "salary")   // 00:00
+	_ = wallet.WithdrawWithMemo(30, "rent") // 01:00
+	_ = wallet.WithdrawWithMemo(500, "car") // 余额不足，不记录
+	wallet.DepositWithMemo(10, "gift")      // 02:00
+	_ = wallet.WithdrawWithMemo(1, "fee")   // 03:00
+	return wallet
+}
+
+func TestHistory(t *testing.T) {
+	wallet := newHistoryWallet(t)
+	history := wallet.History()
+
+	want := []Transaction{
+		{day, DepositTransaction, 100, "salary", 105},
+		{day.Add(time.Hour), WithdrawTransaction, -30, "rent", 75},
+		{day.Add(2 * time.Hour), DepositTransaction, 10, "gift", 85},
+		{day.Add(3 * time.Hour), WithdrawTransaction, -1, "fee", 84},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("got %d transactions, want %d", len(history), len(want))
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("transaction %d: got %+v, want %+v", i, history[i], want[i])
+		}
+	}
+
+	t.Run("transfers are recorded on both sides", func(t *testing.T) {
+		from, to := &Wallet{balance: 10}, &Wallet{}
+		assertNoError(t, Transfer(from, to, 4))
+
+		if h := from.History(); len(h) != 1 || h[0].Kind != TransferOutTransaction || h[0].Balance != 6 {
+			t.Errorf("got %+v", h)
+		}
+		if h := to.History(); len(h) != 1 || h[0].Kind != TransferInTransaction || h[0].Balance != 4 {
+			t.Errorf("got %+v", h)
+		}
+	})
+}
+
+func TestStatement(t *testing.T) {
+	wallet := newHistoryWallet(t)
+
+	t.Run("range", func(t *testing.T) {
+		s := wallet.Statement(day.Add(time.Hour), day.Add(3*time.Hour))
+
+		assertBitcoin(t, s.OpeningBalance, 105)
+		assertBitcoin(t, s.ClosingBalance, 85)
+		if len(s.Transactions) != 2 || s.Transactions[0].Memo != "rent" || s.Transactions[1].Memo != "gift" {
+			t.Errorf("got %+v", s.Transactions)
+		}
+	})
+
+	t.Run("before any transaction", func(t *testing.T) {
+		s := wallet.Statement(day.Add(-time.Hour), day)
+		assertBitcoin(t, s.OpeningBalance, 5)
+		assertBitcoin(t, s.ClosingBalance, 5)
+	})
+
+	t.Run("after all transactions", func(t *testing.T) {
+		s := wallet.Statement(day.Add(24*time.Hour), day.Add(48*time.Hour))
+		assertBitcoin(t, s.OpeningBalance, 84)
+		assertBitcoin(t, s.ClosingBalance, 84)
+		if len(s.Transactions) != 0 {
+			t.Errorf("got %+v", s.Transactions)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := wallet.Statement(day, day.Add(2*time.Hour))
+		assertNoError(t, s.WriteCSV(&buf))
+
+		want := "time,kind,amount,memo,balance\n" +
+			"2024-01-01T00:00:00Z,deposit,100,salary,105\n" +
+			"2024-01-01T01:00:00Z,withdraw,-30,rent,75\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := wallet.Statement(day, day.Add(time.Hour))
+		assertNoError(t, s.WriteJSON(&buf))
+
+		var got Statement
+		assertNoError(t, json.Unmarshal(buf.Bytes(), &got))
+		if got.OpeningBalance != 5 || got.ClosingBalance != 105 || len(got.Transactions) != 1 {
+			t.Errorf("got %+v", got)
+		}
+	})
+}
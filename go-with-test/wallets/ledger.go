@@ -0,0 +1,307 @@
+package wallets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AccountKind 账户类型
+type AccountKind int
+
+const (
+	// WalletAccount 用户钱包，余额不允许为负
+	WalletAccount AccountKind = iota
+	// ExternalAccount 外部账户，表示资金的来源与去向（充值、提现），余额允许为负
+	ExternalAccount
+)
+
+// ExternalAccountID 账本内置的外部账户，Deposit/Withdraw 都以它作为对手方
+const ExternalAccountID = "external"
+
+var (
+	UnknownAccountError  = errors.New("unknown account")
+	AccountExistError    = errors.New("account already exists")
+	UnbalancedEntryError = errors.New("journal entry does not balance")
+	LedgerCorruptedError = errors.New("ledger balances do not match journal")
+	TooFewPostingsError  = errors.New("journal entry needs at least two postings")
+)
+
+// Posting 分录中的一行，Amount 为正表示记入该账户，为负表示从该账户转出
+type Posting struct {
+	Account string
+	Amount  Money
+}
+
+// JournalEntry 一笔复式记账分录，同一币种下所有 Posting 的金额之和必须为 0
+type JournalEntry struct {
+	ID       uint64
+	Time     time.Time
+	Memo     string
+	Postings []Posting
+}
+
+// Ledger 多币种复式记账账本。
+// 账户余额完全由日志推导：balances 只是日志的缓存，可以随时通过 Audit 校验、通过 Replay 重建。
+type Ledger struct {
+	mu       sync.RWMutex
+	accounts map[string]AccountKind
+	journal  []JournalEntry
+	balances map[string]map[Currency]int64
+	nextID   uint64
+	now      func() time.Time
+}
+
+func NewLedger() *Ledger {
+	l := &Ledger{
+		accounts: map[string]AccountKind{},
+		balances: map[string]map[Currency]int64{},
+		nextID:   1,
+		now:      time.Now,
+	}
+	l.accounts[ExternalAccountID] = ExternalAccount
+	return l
+}
+
+func (l *Ledger) OpenAccount(id string, kind AccountKind) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.accounts[id]; ok {
+		return fmt.Errorf("open %q: %w", id, AccountExistError)
+	}
+	l.accounts[id] = kind
+	return nil
+}
+
+// Post 原子地记入一笔分录：要么全部 Posting 生效，要么全部不生效
+func (l *Ledger) Post(memo string, postings ...Posting) (JournalEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := JournalEntry{
+		ID:       l.nextID,
+		Time:     l.now(),
+		Memo:     memo,
+		Postings: append([]Posting(nil), postings...),
+	}
+	if err := l.apply(entry); err != nil {
+		return JournalEntry{}, err
+	}
+	l.nextID++
+	return entry, nil
+}
+
+// Deposit 从外部账户向 account 充值
+func (l *Ledger) Deposit(account string, amount Money, memo string) (JournalEntry, error) {
+	return l.Transfer(ExternalAccountID, account, amount, memo)
+}
+
+// Withdraw 从 account 提现到外部账户
+func (l *Ledger) Withdraw(account string, amount Money, memo string) (JournalEntry, error) {
+	return l.Transfer(account, ExternalAccountID, amount, memo)
+}
+
+// Transfer 在两个账户之间转账，amount 必须为正
+func (l *Ledger) Transfer(from, to string, amount Money, memo string) (JournalEntry, error) {
+	if amount.Units <= 0 {
+		return JournalEntry{}, fmt.Errorf("transfer %s: %w", amount, InvalidAmountError)
+	}
+	return l.Post(memo,
+		Posting{Account: from, Amount: amount.Neg()},
+		Posting{Account: to, Amount: amount},
+	)
+}
+
+// Balance 返回账户在指定币种下的余额
+func (l *Ledger) Balance(account string, currency Currency) Money {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return NewMoney(l.balances[account][currency], currency)
+}
+
+// Balances 返回账户所有非零币种的余额，按币种代码排序
+func (l *Ledger) Balances(account string) []Money {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var out []Money
+	for currency, units := range l.balances[account] {
+		if units != 0 {
+			out = append(out, NewMoney(units, currency))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Currency.Code < out[j].Currency.Code
+	})
+	return out
+}
+
+// Journal 返回日志副本
+func (l *Ledger) Journal() []JournalEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	out := make([]JournalEntry, len(l.journal))
+	for i, e := range l.journal {
+		e.Postings = append([]Posting(nil), e.Postings...)
+		out[i] = e
+	}
+	return out
+}
+
+// Replay 依次重放日志，通常用于从持久化的日志恢复账本。
+// 账户需要事先通过 OpenAccount 开立，任何一笔分录校验失败都会中止重放，账本保持重放前的状态。
+func (l *Ledger) Replay(entries []JournalEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 在副本上重放，全部成功后再替换，journal 限制容量以免 append 写进原来的底层数组
+	scratch := &Ledger{
+		accounts: l.accounts,
+		journal:  l.journal[:len(l.journal):len(l.journal)],
+		balances: make(map[string]map[Currency]int64, len(l.balances)),
+		nextID:   l.nextID,
+	}
+	for id, balances := range l.balances {
+		scratch.balances[id] = make(map[Currency]int64, len(balances))
+		for currency, units := range balances {
+			scratch.balances[id][currency] = units
+		}
+	}
+
+	for _, e := range entries {
+		e.Postings = append([]Posting(nil), e.Postings...)
+		if err := scratch.apply(e); err != nil {
+			return fmt.Errorf("replay entry %d: %w", e.ID, err)
+		}
+		if e.ID >= scratch.nextID {
+			scratch.nextID = e.ID + 1
+		}
+	}
+	l.journal, l.balances, l.nextID = scratch.journal, scratch.balances, scratch.nextID
+	return nil
+}
+
+// Audit 根据日志重新计算所有余额，并与缓存的余额比对
+func (l *Ledger) Audit() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	replayed := &Ledger{
+		accounts: l.accounts,
+		balances: map[string]map[Currency]int64{},
+	}
+	for _, e := range l.journal {
+		if err := replayed.check(e); err != nil {
+			return fmt.Errorf("audit entry %d: %w", e.ID, err)
+		}
+		replayed.commit(e)
+	}
+
+	accounts := map[string]bool{}
+	for id := range l.balances {
+		accounts[id] = true
+	}
+	for id := range replayed.balances {
+		accounts[id] = true
+	}
+	for id := range accounts {
+		if !sameBalances(l.balances[id], replayed.balances[id]) {
+			return fmt.Errorf("audit account %q: %w", id, LedgerCorruptedError)
+		}
+	}
+	return nil
+}
+
+// apply 校验并写入分录，调用方需持有写锁
+func (l *Ledger) apply(e JournalEntry) error {
+	if err := l.check(e); err != nil {
+		return err
+	}
+	l.commit(e)
+	l.journal = append(l.journal, e)
+	return nil
+}
+
+// check 校验分录：账户存在、金额非零、各币种借贷平衡、钱包账户不透支、不溢出
+func (l *Ledger) check(e JournalEntry) error {
+	if len(e.Postings) < 2 {
+		return TooFewPostingsError
+	}
+
+	sums := map[Currency]Money{}
+	after := map[string]map[Currency]Money{}
+	for _, p := range e.Postings {
+		if _, ok := l.accounts[p.Account]; !ok {
+			return fmt.Errorf("account %q: %w", p.Account, UnknownAccountError)
+		}
+		if p.Amount.IsZero() {
+			return fmt.Errorf("account %q: zero posting: %w", p.Account, InvalidAmountError)
+		}
+
+		sum, ok := sums[p.Amount.Currency]
+		if !ok {
+			sum = NewMoney(0, p.Amount.Currency)
+		}
+		sum, err := sum.Add(p.Amount)
+		if err != nil {
+			return err
+		}
+		sums[p.Amount.Currency] = sum
+
+		if after[p.Account] == nil {
+			after[p.Account] = map[Currency]Money{}
+		}
+		balance, ok := after[p.Account][p.Amount.Currency]
+		if !ok {
+			balance = NewMoney(l.balances[p.Account][p.Amount.Currency], p.Amount.Currency)
+		}
+		if balance, err = balance.Add(p.Amount); err != nil {
+			return err
+		}
+		after[p.Account][p.Amount.Currency] = balance
+	}
+
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("%s off by %s: %w", currency, sum, UnbalancedEntryError)
+		}
+	}
+	for account, balances := range after {
+		if l.accounts[account] != WalletAccount {
+			continue
+		}
+		for _, balance := range balances {
+			if balance.Units < 0 {
+				return fmt.Errorf("account %q: %w", account, InsufficientFundsError)
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Ledger) commit(e JournalEntry) {
+	for _, p := range e.Postings {
+		if l.balances[p.Account] == nil {
+			l.balances[p.Account] = map[Currency]int64{}
+		}
+		l.balances[p.Account][p.Amount.Currency] += p.Amount.Units
+	}
+}
+
+func sameBalances(a, b map[Currency]int64) bool {
+	for currency, units := range a {
+		if b[currency] != units {
+			return false
+		}
+	}
+	for currency, units := range b {
+		if a[currency] != units {
+			return false
+		}
+	}
+	return true
+}
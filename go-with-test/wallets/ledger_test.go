@@ -0,0 +1,169 @@
+package wallets
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestLedger(t *testing.T, accounts ...string) *Ledger {
+	t.Helper()
+	l := NewLedger()
+	for _, id := range accounts {
+		if err := l.OpenAccount(id, WalletAccount); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+func assertMoney(t *testing.T, got, want Money) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func assertErrorIs(t *testing.T, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+}
+
+func TestLedger(t *testing.T) {
+	t.Run("deposit and withdraw", func(t *testing.T) {
+		l := newTestLedger(t, "alice")
+
+		_, err := l.Deposit("alice", NewMoney(150, BTC), "salary")
+		assertNoError(t, err)
+		_, err = l.Withdraw("alice", NewMoney(50, BTC), "coffee")
+		assertNoError(t, err)
+
+		assertMoney(t, l.Balance("alice", BTC), NewMoney(100, BTC))
+		assertMoney(t, l.Balance(ExternalAccountID, BTC), NewMoney(-100, BTC))
+		assertNoError(t, l.Audit())
+	})
+
+	t.Run("multiple currencies", func(t *testing.T) {
+		l := newTestLedger(t, "alice")
+		_, _ = l.Deposit("alice", NewMoney(1, BTC), "")
+		_, _ = l.Deposit("alice", NewMoney(500, USD), "")
+
+		got := l.Balances("alice")
+		if len(got) != 2 || got[0] != NewMoney(1, BTC) || got[1] != NewMoney(500, USD) {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		l := newTestLedger(t, "alice", "bob")
+		_, _ = l.Deposit("alice", NewMoney(10, BTC), "")
+
+		_, err := l.Transfer("alice", "bob", NewMoney(11, BTC), "")
+
+		assertErrorIs(t, err, InsufficientFundsError)
+		assertMoney(t, l.Balance("alice", BTC), NewMoney(10, BTC))
+		assertMoney(t, l.Balance("bob", BTC), NewMoney(0, BTC))
+		if len(l.Journal()) != 1 {
+			t.Errorf("failed transfer must not be journaled")
+		}
+	})
+
+	t.Run("atomic multi-leg entry", func(t *testing.T) {
+		l := newTestLedger(t, "alice", "bob", "carol")
+		_, _ = l.Deposit("alice", NewMoney(100, USD), "")
+
+		// 第二条腿透支，整笔分录都不应生效
+		_, err := l.Post("split",
+			Posting{Account: "alice", Amount: NewMoney(-100, USD)},
+			Posting{Account: "bob", Amount: NewMoney(150, USD)},
+			Posting{Account: "carol", Amount: NewMoney(-50, USD)},
+		)
+		assertErrorIs(t, err, InsufficientFundsError)
+		assertMoney(t, l.Balance("alice", USD), NewMoney(100, USD))
+		assertMoney(t, l.Balance("bob", USD), NewMoney(0, USD))
+	})
+
+	t.Run("rejects invalid entries", func(t *testing.T) {
+		l := newTestLedger(t, "alice", "bob")
+		_, _ = l.Deposit("alice", NewMoney(100, USD), "")
+
+		_, err := l.Post("unbalanced",
+			Posting{Account: "alice", Amount: NewMoney(-10, USD)},
+			Posting{Account: "bob", Amount: NewMoney(9, USD)},
+		)
+		assertErrorIs(t, err, UnbalancedEntryError)
+
+		_, err = l.Post("mixed",
+			Posting{Account: "alice", Amount: NewMoney(-10, USD)},
+			Posting{Account: "bob", Amount: NewMoney(10, CNY)},
+		)
+		assertErrorIs(t, err, UnbalancedEntryError)
+
+		_, err = l.Post("single", Posting{Account: "alice", Amount: NewMoney(1, USD)})
+		assertErrorIs(t, err, TooFewPostingsError)
+
+		_, err = l.Transfer("alice", "nobody", NewMoney(1, USD), "")
+		assertErrorIs(t, err, UnknownAccountError)
+
+		_, err = l.Transfer("alice", "bob", NewMoney(0, USD), "")
+		assertErrorIs(t, err, InvalidAmountError)
+
+		assertErrorIs(t, l.OpenAccount("alice", WalletAccount), AccountExistError)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		l := newTestLedger(t, "alice", "bob")
+		_, _ = l.Deposit("alice", Bitcoin(2).Satoshis(), "")
+		_, _ = l.Transfer("alice", "bob", NewMoney(SatoshiPerBitcoin/2, BTC), "")
+
+		restored := newTestLedger(t, "alice", "bob")
+		assertNoError(t, restored.Replay(l.Journal()))
+
+		assertMoney(t, restored.Balance("alice", BTC), l.Balance("alice", BTC))
+		assertMoney(t, restored.Balance("bob", BTC), l.Balance("bob", BTC))
+
+		entry, err := restored.Deposit("bob", NewMoney(1, BTC), "")
+		assertNoError(t, err)
+		if entry.ID != 3 {
+			t.Errorf("got entry id %d, want %d", entry.ID, 3)
+		}
+	})
+
+	t.Run("failed replay leaves the ledger unchanged", func(t *testing.T) {
+		l := newTestLedger(t, "alice")
+		_, _ = l.Deposit("alice", NewMoney(100, USD), "")
+
+		entries := []JournalEntry{
+			{ID: 2, Postings: []Posting{
+				{Account: ExternalAccountID, Amount: NewMoney(-50, USD)},
+				{Account: "alice", Amount: NewMoney(50, USD)},
+			}},
+			{ID: 3, Postings: []Posting{
+				{Account: ExternalAccountID, Amount: NewMoney(-1, USD)},
+				{Account: "nobody", Amount: NewMoney(1, USD)},
+			}},
+		}
+		assertErrorIs(t, l.Replay(entries), UnknownAccountError)
+
+		assertMoney(t, l.Balance("alice", USD), NewMoney(100, USD))
+		if got := len(l.Journal()); got != 1 {
+			t.Errorf("journal has %d entries, want 1", got)
+		}
+		assertNoError(t, l.Audit())
+		entry, err := l.Deposit("alice", NewMoney(1, USD), "")
+		assertNoError(t, err)
+		if entry.ID != 2 {
+			t.Errorf("got entry id %d, want %d", entry.ID, 2)
+		}
+	})
+
+	t.Run("audit detects tampering", func(t *testing.T) {
+		l := newTestLedger(t, "alice")
+		_, _ = l.Deposit("alice", NewMoney(100, USD), "")
+
+		l.balances["alice"][USD] += 1
+
+		assertErrorIs(t, l.Audit(), LedgerCorruptedError)
+	})
+}
@@ -0,0 +1,137 @@
+package wallets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Currency 币种，Decimals 为最小单位的小数位数，例如 BTC 的最小单位为聪(satoshi)，1 BTC = 10^8 satoshi
+type Currency struct {
+	Code     string
+	Decimals int
+}
+
+var (
+	BTC = Currency{Code: "BTC", Decimals: 8}
+	USD = Currency{Code: "USD", Decimals: 2}
+	CNY = Currency{Code: "CNY", Decimals: 2}
+)
+
+// SatoshiPerBitcoin 1 BTC 对应的聪数
+const SatoshiPerBitcoin = 100_000_000
+
+func (c Currency) String() string {
+	return c.Code
+}
+
+// unit 返回 1 个完整货币单位对应的最小单位数量
+func (c Currency) unit() int64 {
+	u := int64(1)
+	for i := 0; i < c.Decimals; i++ {
+		u *= 10
+	}
+	return u
+}
+
+var (
+	CurrencyMismatchError = errors.New("currency mismatch")
+	AmountOverflowError   = errors.New("amount overflow")
+	InvalidAmountError    = errors.New("invalid amount")
+)
+
+// Money 金额，以最小单位的整数存储，避免浮点误差
+type Money struct {
+	Units    int64
+	Currency Currency
+}
+
+// NewMoney 用最小单位构造金额，例如 NewMoney(1, BTC) 表示 1 聪
+func NewMoney(units int64, currency Currency) Money {
+	return Money{Units: units, Currency: currency}
+}
+
+// Satoshis 将整数 Bitcoin 换算为以聪为单位的金额
+func (b Bitcoin) Satoshis() Money {
+	return NewMoney(int64(b)*SatoshiPerBitcoin, BTC)
+}
+
+// ParseMoney 解析十进制字符串，例如 ParseMoney("0.00000001", BTC)
+func ParseMoney(s string, currency Currency) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Money{}, fmt.Errorf("parse %q: %w", s, InvalidAmountError)
+	}
+	if len(fracPart) > currency.Decimals {
+		return Money{}, fmt.Errorf("parse %q: more than %d decimals for %s: %w", s, currency.Decimals, currency, InvalidAmountError)
+	}
+	fracPart += strings.Repeat("0", currency.Decimals-len(fracPart))
+
+	var units int64
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("parse %q: %w", s, InvalidAmountError)
+		}
+		if units > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("parse %q: %w", s, AmountOverflowError)
+		}
+		units = units*10 + int64(r-'0')
+	}
+	if neg {
+		units = -units
+	}
+	return NewMoney(units, currency), nil
+}
+
+// String 按币种精度输出，例如 "0.00000010 BTC"
+func (m Money) String() string {
+	units := m.Units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+	// 取绝对值时避免 MinInt64 溢出
+	abs := uint64(units)
+	if units < 0 {
+		abs = uint64(-(units + 1)) + 1
+	}
+
+	if m.Currency.Decimals == 0 {
+		return fmt.Sprintf("%s%d %s", sign, abs, m.Currency)
+	}
+	unit := uint64(m.Currency.unit())
+	return fmt.Sprintf("%s%d.%0*d %s", sign, abs/unit, m.Currency.Decimals, abs%unit, m.Currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.Units == 0
+}
+
+func (m Money) Neg() Money {
+	return NewMoney(-m.Units, m.Currency)
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%s + %s: %w", m.Currency, o.Currency, CurrencyMismatchError)
+	}
+	if (o.Units > 0 && m.Units > math.MaxInt64-o.Units) || (o.Units < 0 && m.Units < math.MinInt64-o.Units) {
+		return Money{}, fmt.Errorf("%s + %s: %w", m, o, AmountOverflowError)
+	}
+	return NewMoney(m.Units+o.Units, m.Currency), nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Units == math.MinInt64 {
+		return Money{}, fmt.Errorf("%s - %s: %w", m, o, AmountOverflowError)
+	}
+	return m.Add(o.Neg())
+}
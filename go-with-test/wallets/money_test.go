@@ -0,0 +1,82 @@
+package wallets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		input    string
+		currency Currency
+		want     int64
+	}{
+		{"1", BTC, SatoshiPerBitcoin},
+		{"0.00000001", BTC, 1},
+		{"21000000.5", BTC, 2100000050000000},
+		{"-1.5", USD, -150},
+		{".25", CNY, 25},
+		{"3.", USD, 300},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got, err := ParseMoney(c.input, c.currency)
+			assertNoError(t, err)
+			if got != NewMoney(c.want, c.currency) {
+				t.Errorf("got %s, want %s", got, NewMoney(c.want, c.currency))
+			}
+		})
+	}
+
+	for _, input := range []string{"", "-", ".", "1.001", "1e3", "abc", "99999999999999999999"} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := ParseMoney(input, USD)
+			if err == nil {
+				t.Errorf("expected an error for %q", input)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	cases := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(10, BTC), "0.00000010 BTC"},
+		{Bitcoin(3).Satoshis(), "3.00000000 BTC"},
+		{NewMoney(-1999, USD), "-19.99 USD"},
+		{NewMoney(5, Currency{Code: "JPY"}), "5 JPY"},
+	}
+
+	for _, c := range cases {
+		if got := c.money.String(); got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		got, err := NewMoney(1, BTC).Add(NewMoney(2, BTC))
+		assertNoError(t, err)
+		if got != NewMoney(3, BTC) {
+			t.Errorf("got %s, want %s", got, NewMoney(3, BTC))
+		}
+	})
+
+	t.Run("currency mismatch", func(t *testing.T) {
+		_, err := NewMoney(1, BTC).Add(NewMoney(1, USD))
+		if !errors.Is(err, CurrencyMismatchError) {
+			t.Errorf("got %v, want %v", err, CurrencyMismatchError)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := NewMoney(1<<62, BTC).Add(NewMoney(1<<62, BTC))
+		if !errors.Is(err, AmountOverflowError) {
+			t.Errorf("got %v, want %v", err, AmountOverflowError)
+		}
+	})
+}
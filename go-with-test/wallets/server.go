@@ -0,0 +1,383 @@
+package wallets
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jsonContentType = "application/json"
+	// IdempotencyKeyHeader 客户端为资金类请求携带的幂等键，重试时复用同一个键不会重复扣款
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 响应来自幂等缓存时设置为 true
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// MaxRequestBodyBytes 请求体的大小上限，超出时返回 413
+	MaxRequestBodyBytes = 1 << 20
+)
+
+// WalletServer 钱包 HTTP API
+//
+//	GET  /wallets/{id}
+//	GET  /wallets/{id}/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=csv
+//	POST /wallets/{id}/deposit   {"amount": 10}
+//	POST /wallets/{id}/withdraw  {"amount": 10}
+//	POST /transfers              {"from": "a", "to": "b", "amount": 10}
+type WalletServer struct {
+	store WalletStore
+	// idempotency 只保存在内存中，服务重启后幂等键失效，见 idempotencyCache
+	idempotency *idempotencyCache
+	http.Handler
+}
+
+type WalletResponse struct {
+	ID      string  `json:"id"`
+	Balance Bitcoin `json:"balance"`
+}
+
+type AmountRequest struct {
+	Amount Bitcoin `json:"amount"`
+}
+
+type TransferRequest struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount Bitcoin `json:"amount"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewWalletServer(store WalletStore) *WalletServer {
+	s := &WalletServer{store: store, idempotency: newIdempotencyCache()}
+
+	router := http.NewServeMux()
+	router.Handle("/wallets/", http.HandlerFunc(s.walletHandler))
+	router.Handle("/transfers", http.HandlerFunc(s.transferHandler))
+	s.Handler = router
+
+	return s
+}
+
+// walletResult 处理结果，由 respond 统一编码，方便幂等缓存记录
+type walletResult struct {
+	status  int
+	payload interface{}
+}
+
+func (s *WalletServer) walletHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/wallets/"):], "/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 {
+		respond(w, errorResult(http.StatusNotFound, "not found"))
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			respond(w, errorResult(http.StatusMethodNotAllowed, "method not allowed"))
+			return
+		}
+		respond(w, s.showWallet(id))
+		return
+	}
+
+	if parts[1] == "statement" {
+		s.statementHandler(w, r, id)
+		return
+	}
+
+	var op func(string, Bitcoin) (Bitcoin, error)
+	switch parts[1] {
+	case "deposit":
+		op = s.store.Deposit
+	case "withdraw":
+		op = s.store.Withdraw
+	default:
+		respond(w, errorResult(http.StatusNotFound, "not found"))
+		return
+	}
+	s.mutate(w, r, func(body []byte) walletResult {
+		var req AmountRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errorResult(http.StatusBadRequest, "invalid request body")
+		}
+		balance, err := op(id, req.Amount)
+		if err != nil {
+			return storeErrorResult(err)
+		}
+		return walletResult{http.StatusOK, WalletResponse{ID: id, Balance: balance}}
+	})
+}
+
+func (s *WalletServer) transferHandler(w http.ResponseWriter, r *http.Request) {
+	s.mutate(w, r, func(body []byte) walletResult {
+		var req TransferRequest
+		if err := json.Unmarshal(body, &req); err != nil || req.From == "" || req.To == "" {
+			return errorResult(http.StatusBadRequest, "invalid request body")
+		}
+		if err := s.store.Transfer(req.From, req.To, req.Amount); err != nil {
+			return storeErrorResult(err)
+		}
+		return walletResult{http.StatusOK, req}
+	})
+}
+
+// statementHandler 返回对账单，from 默认为最早，to 默认为当前时间，format 为 json（默认）或 csv
+func (s *WalletServer) statementHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		respond(w, errorResult(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	query := r.URL.Query()
+	from, to := time.Time{}, time.Now()
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respond(w, errorResult(http.StatusBadRequest, "invalid "+name+": "+err.Error()))
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	statement, err := s.store.Statement(id, from, to)
+	if err != nil {
+		respond(w, storeErrorResult(err))
+		return
+	}
+	switch query.Get("format") {
+	case "", "json":
+		w.Header().Set("content-type", jsonContentType)
+		_ = statement.WriteJSON(w)
+	case "csv":
+		w.Header().Set("content-type", "text/csv")
+		_ = statement.WriteCSV(w)
+	default:
+		respond(w, errorResult(http.StatusBadRequest, "unsupported format"))
+	}
+}
+
+func (s *WalletServer) showWallet(id string) walletResult {
+	balance, err := s.store.Balance(id)
+	if err != nil {
+		return storeErrorResult(err)
+	}
+	return walletResult{http.StatusOK, WalletResponse{ID: id, Balance: balance}}
+}
+
+// mutate 处理资金类 POST 请求，携带幂等键时同一个键只会真正执行一次
+func (s *WalletServer) mutate(w http.ResponseWriter, r *http.Request, handle func(body []byte) walletResult) {
+	if r.Method != http.MethodPost {
+		respond(w, errorResult(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respond(w, errorResult(http.StatusRequestEntityTooLarge, "request body too large"))
+		return
+	}
+	if err != nil {
+		respond(w, errorResult(http.StatusBadRequest, "invalid request body"))
+		return
+	}
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		respond(w, handle(body))
+		return
+	}
+
+	fingerprint := requestFingerprint(r, body)
+	entry, replay, err := s.idempotency.begin(key, fingerprint)
+	switch {
+	case errors.Is(err, idempotencyKeyReusedError):
+		respond(w, errorResult(http.StatusUnprocessableEntity, err.Error()))
+		return
+	case errors.Is(err, idempotencyKeyInFlightError):
+		respond(w, errorResult(http.StatusConflict, err.Error()))
+		return
+	case errors.Is(err, idempotencyCacheFullError):
+		respond(w, errorResult(http.StatusServiceUnavailable, err.Error()))
+		return
+	case replay:
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		writeJSON(w, entry.status, entry.body)
+		return
+	}
+
+	result := handle(body)
+	data, _ := json.Marshal(result.payload)
+	// 5xx 视为临时故障，不缓存，允许客户端用同一个键重试
+	s.idempotency.finish(entry, result.status < http.StatusInternalServerError, result.status, data)
+	writeJSON(w, result.status, data)
+}
+
+func errorResult(status int, msg string) walletResult {
+	return walletResult{status, ErrorResponse{Error: msg}}
+}
+
+func storeErrorResult(err error) walletResult {
+	switch {
+	case errors.Is(err, WalletNotFoundError):
+		return errorResult(http.StatusNotFound, err.Error())
+	case errors.Is(err, InsufficientFundsError):
+		return errorResult(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, InvalidAmountError):
+		return errorResult(http.StatusBadRequest, err.Error())
+	default:
+		return errorResult(http.StatusInternalServerError, err.Error())
+	}
+}
+
+func respond(w http.ResponseWriter, result walletResult) {
+	data, _ := json.Marshal(result.payload)
+	writeJSON(w, result.status, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data []byte) {
+	// header 必须在 WriteHeader 之前设置
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+	_, _ = w.Write([]byte("\n"))
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.Sum256(bytes.Join([][]byte{[]byte(r.Method), []byte(r.URL.Path), body}, []byte{0}))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	idempotencyKeyReusedError   = errors.New("idempotency key reused with a different request")
+	idempotencyKeyInFlightError = errors.New("a request with this idempotency key is in progress")
+	idempotencyCacheFullError   = errors.New("too many requests with idempotency keys in progress")
+)
+
+const (
+	// DefaultIdempotencyTTL 幂等键默认保留的时间，客户端应在此之内完成重试
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyMaxKeys 默认最多保留的幂等键数量，超出时淘汰最早完成的，
+	// 处理中的键不会被淘汰，全部是处理中的键时新的键返回 503
+	DefaultIdempotencyMaxKeys = 10000
+)
+
+type idempotentResponse struct {
+	key         string
+	fingerprint string
+	created     time.Time
+	done        bool
+	status      int
+	body        []byte
+}
+
+// idempotencyCache 按创建顺序保存幂等键，过期或超出数量上限的从最早的开始淘汰，处理中的键除外：
+// 淘汰后同一个键的重试会与仍在处理的请求并发执行。
+// 只保存在内存中，幂等只在同一个进程的生命周期内成立：服务重启后，即使使用的是持久化的存储，
+// 客户端用原来的键重试也会再执行一次。
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxKeys int
+	now     func() time.Time
+	entries map[string]*list.Element
+	// order 元素为 *idempotentResponse，最早创建的在前
+	order *list.List
+}
+
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{
+		ttl:     DefaultIdempotencyTTL,
+		maxKeys: DefaultIdempotencyMaxKeys,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// SetIdempotencyLimits 设置幂等键的保留时间和最大数量
+func (s *WalletServer) SetIdempotencyLimits(ttl time.Duration, maxKeys int) {
+	s.idempotency.mu.Lock()
+	defer s.idempotency.mu.Unlock()
+	s.idempotency.ttl, s.idempotency.maxKeys = ttl, maxKeys
+	s.idempotency.evict()
+}
+
+// begin 占用幂等键。replay 为 true 表示该键已处理完成，直接回放 entry 即可；
+// 否则返回新占用的 entry，处理完成后交给 finish
+func (c *idempotencyCache) begin(key, fingerprint string) (entry *idempotentResponse, replay bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+
+	if elem, ok := c.entries[key]; ok {
+		entry = elem.Value.(*idempotentResponse)
+		if entry.fingerprint != fingerprint {
+			return nil, false, idempotencyKeyReusedError
+		}
+		if !entry.done {
+			return nil, false, idempotencyKeyInFlightError
+		}
+		return entry, true, nil
+	}
+
+	entry = &idempotentResponse{key: key, fingerprint: fingerprint, created: c.now()}
+	elem := c.order.PushBack(entry)
+	c.entries[key] = elem
+	c.evict()
+	if c.order.Len() > c.maxKeys {
+		// 剩下的都是处理中的键
+		c.remove(elem)
+		return nil, false, idempotencyCacheFullError
+	}
+	return entry, false, nil
+}
+
+// finish 记录处理结果，keep 为 false 时释放幂等键
+func (c *idempotencyCache) finish(entry *idempotentResponse, keep bool, status int, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[entry.key]
+	if !ok || elem.Value != entry {
+		// 处理期间已经被淘汰
+		return
+	}
+	if !keep {
+		c.remove(elem)
+		return
+	}
+	entry.done, entry.status, entry.body = true, status, body
+}
+
+// evict 淘汰过期和超出数量上限的已完成的幂等键，调用方需持有 c.mu
+func (c *idempotencyCache) evict() {
+	expired := c.now().Add(-c.ttl)
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*idempotentResponse)
+		if c.order.Len() <= c.maxKeys && entry.created.After(expired) {
+			return
+		}
+		if entry.done {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func (c *idempotencyCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*idempotentResponse).key)
+	c.order.Remove(elem)
+}
@@ -0,0 +1,240 @@
+package wallets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newWalletRequest(method, path, body, idempotencyKey string) *http.Request {
+	request, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if idempotencyKey != "" {
+		request.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	return request
+}
+
+func serve(server *WalletServer, request *http.Request) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func assertStatus(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got status %d, want %d", got, want)
+	}
+}
+
+func assertWalletResponse(t *testing.T, response *httptest.ResponseRecorder, want WalletResponse) {
+	t.Helper()
+	if ct := response.Header().Get("content-type"); ct != jsonContentType {
+		t.Errorf("got content-type %q, want %q", ct, jsonContentType)
+	}
+	var got WalletResponse
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("unable to parse response %q: %v", response.Body, err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func amountBody(amount Bitcoin) string {
+	return fmt.Sprintf(`{"amount": %d}`, amount)
+}
+
+func TestWalletServer(t *testing.T) {
+	t.Run("get unknown wallet", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		response := serve(server, newWalletRequest(http.MethodGet, "/wallets/alice", "", ""))
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("deposit, withdraw and get", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+
+		response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), ""))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertWalletResponse(t, response, WalletResponse{ID: "alice", Balance: 20})
+
+		response = serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/withdraw", amountBody(5), ""))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertWalletResponse(t, response, WalletResponse{ID: "alice", Balance: 15})
+
+		response = serve(server, newWalletRequest(http.MethodGet, "/wallets/alice", "", ""))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertWalletResponse(t, response, WalletResponse{ID: "alice", Balance: 15})
+	})
+
+	t.Run("insufficient funds is 422", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), ""))
+
+		response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/withdraw", amountBody(21), ""))
+
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		var got ErrorResponse
+		_ = json.NewDecoder(response.Body).Decode(&got)
+		if got.Error != InsufficientFundsError.Error() {
+			t.Errorf("got error %q, want %q", got.Error, InsufficientFundsError)
+		}
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), ""))
+
+		response := serve(server, newWalletRequest(http.MethodPost, "/transfers", `{"from": "alice", "to": "bob", "amount": 7}`, ""))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		response = serve(server, newWalletRequest(http.MethodGet, "/wallets/bob", "", ""))
+		assertWalletResponse(t, response, WalletResponse{ID: "bob", Balance: 7})
+
+		response = serve(server, newWalletRequest(http.MethodPost, "/transfers", `{"from": "alice", "to": "bob", "amount": 100}`, ""))
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		cases := []struct {
+			request *http.Request
+			want    int
+		}{
+			{newWalletRequest(http.MethodPost, "/wallets/alice/deposit", "not json", ""), http.StatusBadRequest},
+			{newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(-1), ""), http.StatusBadRequest},
+			{newWalletRequest(http.MethodGet, "/wallets/alice/deposit", "", ""), http.StatusMethodNotAllowed},
+			{newWalletRequest(http.MethodPost, "/wallets/alice", "", ""), http.StatusMethodNotAllowed},
+			{newWalletRequest(http.MethodPost, "/wallets/alice/steal", amountBody(1), ""), http.StatusNotFound},
+			{newWalletRequest(http.MethodPost, "/transfers", `{"amount": 1}`, ""), http.StatusBadRequest},
+		}
+		for _, c := range cases {
+			assertStatus(t, serve(server, c.request).Code, c.want)
+		}
+	})
+
+	t.Run("idempotency key", func(t *testing.T) {
+		store := NewInMemoryWalletStore()
+		server := NewWalletServer(store)
+
+		for i := 0; i < 3; i++ {
+			response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), "key-1"))
+			assertStatus(t, response.Code, http.StatusOK)
+			assertWalletResponse(t, response, WalletResponse{ID: "alice", Balance: 20})
+			if replayed := response.Header().Get(IdempotentReplayedHeader) == "true"; replayed != (i > 0) {
+				t.Errorf("attempt %d: got replayed=%v", i, replayed)
+			}
+		}
+
+		balance, _ := store.Balance("alice")
+		assertBitcoin(t, balance, 20)
+
+		response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(30), "key-1"))
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+
+		response = serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), "key-2"))
+		assertWalletResponse(t, response, WalletResponse{ID: "alice", Balance: 40})
+	})
+	t.Run("idempotency keys expire and are bounded", func(t *testing.T) {
+		store := NewInMemoryWalletStore()
+		server := NewWalletServer(store)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		server.idempotency.now = func() time.Time { return now }
+		server.SetIdempotencyLimits(time.Hour, 2)
+
+		deposit := func(key string) *httptest.ResponseRecorder {
+			return serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(1), key))
+		}
+		deposit("a")
+		deposit("b")
+		if deposit("a").Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Error("key a should still be cached")
+		}
+
+		// 超出上限时淘汰最早的 a
+		deposit("c")
+		if got := len(server.idempotency.entries); got != 2 {
+			t.Errorf("cache holds %d keys, want 2", got)
+		}
+		if deposit("a").Header().Get(IdempotentReplayedHeader) == "true" {
+			t.Error("evicted key should be executed again")
+		}
+
+		now = now.Add(time.Hour)
+		deposit("x")
+		if got := len(server.idempotency.entries); got != 1 {
+			t.Errorf("expired keys should be swept, %d left", got)
+		}
+		balance, _ := store.Balance("alice")
+		assertBitcoin(t, balance, 5)
+	})
+	t.Run("in-flight idempotency keys are not evicted", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		server.SetIdempotencyLimits(time.Hour, 2)
+		a, _, _ := server.idempotency.begin("a", "fingerprint")
+		if _, _, err := server.idempotency.begin("b", "fingerprint"); err != nil {
+			t.Fatal(err)
+		}
+
+		response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(1), "c"))
+		assertStatus(t, response.Code, http.StatusServiceUnavailable)
+		if _, ok := server.idempotency.entries["a"]; !ok {
+			t.Error("in-flight key a was evicted")
+		}
+
+		// a 完成后可以淘汰
+		server.idempotency.finish(a, true, http.StatusOK, nil)
+		response = serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(1), "c"))
+		assertStatus(t, response.Code, http.StatusOK)
+		if _, ok := server.idempotency.entries["a"]; ok {
+			t.Error("finished key a should be evicted")
+		}
+		if _, ok := server.idempotency.entries["b"]; !ok {
+			t.Error("in-flight key b was evicted")
+		}
+	})
+	t.Run("body too large", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		body := `{"amount": 1, "padding": "` + strings.Repeat("x", MaxRequestBodyBytes) + `"}`
+		response := serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", body, ""))
+		assertStatus(t, response.Code, http.StatusRequestEntityTooLarge)
+	})
+	t.Run("statement", func(t *testing.T) {
+		server := NewWalletServer(NewInMemoryWalletStore())
+		serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/deposit", amountBody(20), ""))
+		serve(server, newWalletRequest(http.MethodPost, "/wallets/alice/withdraw", amountBody(5), ""))
+
+		response := serve(server, newWalletRequest(http.MethodGet, "/wallets/alice/statement", "", ""))
+		assertStatus(t, response.Code, http.StatusOK)
+		var statement Statement
+		if err := json.NewDecoder(response.Body).Decode(&statement); err != nil {
+			t.Fatal(err)
+		}
+		if len(statement.Transactions) != 2 || statement.ClosingBalance != 15 {
+			t.Errorf("got %+v", statement)
+		}
+
+		response = serve(server, newWalletRequest(http.MethodGet, "/wallets/alice/statement?format=csv&from=2000-01-01T00:00:00Z", "", ""))
+		assertStatus(t, response.Code, http.StatusOK)
+		if lines := strings.Split(strings.TrimSpace(response.Body.String()), "\n"); len(lines) != 3 {
+			t.Errorf("got %q", response.Body.String())
+		}
+
+		cases := []struct {
+			path string
+			want int
+		}{
+			{"/wallets/bob/statement", http.StatusNotFound},
+			{"/wallets/alice/statement?from=yesterday", http.StatusBadRequest},
+			{"/wallets/alice/statement?format=xml", http.StatusBadRequest},
+		}
+		for _, c := range cases {
+			assertStatus(t, serve(server, newWalletRequest(http.MethodGet, c.path, "", "")).Code, c.want)
+		}
+	})
+}
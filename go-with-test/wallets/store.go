@@ -0,0 +1,123 @@
+package wallets
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var WalletNotFoundError = errors.New("wallet not found")
+
+// WalletStore 钱包存储，所有资金操作都通过存储完成，以便持久化实现可以在变更后落盘
+type WalletStore interface {
+	// Balance 返回钱包余额，钱包不存在时返回 WalletNotFoundError
+	Balance(id string) (Bitcoin, error)
+	// Deposit 存款并返回新余额，钱包不存在时自动创建
+	Deposit(id string, amount Bitcoin) (Bitcoin, error)
+	// Withdraw 取款并返回新余额
+	Withdraw(id string, amount Bitcoin) (Bitcoin, error)
+	// Transfer 在两个钱包间转账，目标钱包不存在时自动创建
+	Transfer(from, to string, amount Bitcoin) error
+	// Statement 返回钱包 [from, to) 区间的对账单
+	Statement(id string, from, to time.Time) (Statement, error)
+}
+
+// InMemoryWalletStore 基于并发安全的 Wallet 实现，进程退出后数据丢失
+type InMemoryWalletStore struct {
+	mu      sync.RWMutex
+	wallets map[string]*Wallet
+}
+
+func NewInMemoryWalletStore() *InMemoryWalletStore {
+	return &InMemoryWalletStore{wallets: map[string]*Wallet{}}
+}
+
+func (s *InMemoryWalletStore) Balance(id string) (Bitcoin, error) {
+	w, err := s.wallet(id)
+	if err != nil {
+		return 0, err
+	}
+	return w.Balance(), nil
+}
+
+func (s *InMemoryWalletStore) Statement(id string, from, to time.Time) (Statement, error) {
+	w, err := s.wallet(id)
+	if err != nil {
+		return Statement{}, err
+	}
+	return w.Statement(from, to), nil
+}
+
+func (s *InMemoryWalletStore) Deposit(id string, amount Bitcoin) (Bitcoin, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("deposit %s: %w", amount, InvalidAmountError)
+	}
+	w := s.walletOrCreate(id)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deposit(amount, DepositTransaction, "")
+	return w.balance, nil
+}
+
+func (s *InMemoryWalletStore) Withdraw(id string, amount Bitcoin) (Bitcoin, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("withdraw %s: %w", amount, InvalidAmountError)
+	}
+	w, err := s.wallet(id)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.withdraw(amount, WithdrawTransaction, ""); err != nil {
+		return w.balance, err
+	}
+	return w.balance, nil
+}
+
+// Transfer 持有 s.mu 完成转账，转账失败时撤销新建的目标钱包，其他请求看不到它
+func (s *InMemoryWalletStore) Transfer(from, to string, amount Bitcoin) error {
+	if amount <= 0 {
+		return fmt.Errorf("transfer %s: %w", amount, InvalidAmountError)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.wallets[from]
+	if !ok {
+		return fmt.Errorf("wallet %q: %w", from, WalletNotFoundError)
+	}
+	dst, ok := s.wallets[to]
+	if !ok {
+		dst = &Wallet{}
+		s.wallets[to] = dst
+	}
+	err := Transfer(src, dst, amount)
+	if err != nil && !ok {
+		delete(s.wallets, to)
+	}
+	return err
+}
+
+func (s *InMemoryWalletStore) wallet(id string) (*Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w, ok := s.wallets[id]
+	if !ok {
+		return nil, fmt.Errorf("wallet %q: %w", id, WalletNotFoundError)
+	}
+	return w, nil
+}
+
+func (s *InMemoryWalletStore) walletOrCreate(id string) *Wallet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.wallets[id]
+	if !ok {
+		w = &Wallet{}
+		s.wallets[id] = w
+	}
+	return w
+}
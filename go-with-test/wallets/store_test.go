@@ -0,0 +1,181 @@
+package wallets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func assertBitcoin(t *testing.T, got, want Bitcoin) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func testWalletStore(t *testing.T, newStore func(t *testing.T) WalletStore) {
+	t.Run("unknown wallet", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.Balance("alice")
+		if !errors.Is(err, WalletNotFoundError) {
+			t.Errorf("got %v, want %v", err, WalletNotFoundError)
+		}
+		_, err = store.Withdraw("alice", 1)
+		if !errors.Is(err, WalletNotFoundError) {
+			t.Errorf("got %v, want %v", err, WalletNotFoundError)
+		}
+	})
+
+	t.Run("deposit and withdraw", func(t *testing.T) {
+		store := newStore(t)
+
+		balance, err := store.Deposit("alice", 20)
+		assertNoError(t, err)
+		assertBitcoin(t, balance, 20)
+
+		balance, err = store.Withdraw("alice", 5)
+		assertNoError(t, err)
+		assertBitcoin(t, balance, 15)
+
+		_, err = store.Withdraw("alice", 16)
+		assertError(t, err, InsufficientFundsError)
+
+		balance, _ = store.Balance("alice")
+		assertBitcoin(t, balance, 15)
+	})
+
+	t.Run("negative amount", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.Deposit("alice", -1)
+		if !errors.Is(err, InvalidAmountError) {
+			t.Errorf("got %v, want %v", err, InvalidAmountError)
+		}
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		store := newStore(t)
+		_, _ = store.Deposit("alice", 20)
+
+		assertNoError(t, store.Transfer("alice", "bob", 8))
+		assertError(t, store.Transfer("alice", "bob", 13), InsufficientFundsError)
+
+		alice, _ := store.Balance("alice")
+		bob, _ := store.Balance("bob")
+		assertBitcoin(t, alice, 12)
+		assertBitcoin(t, bob, 8)
+	})
+
+	t.Run("failed transfer does not create the destination", func(t *testing.T) {
+		store := newStore(t)
+		_, _ = store.Deposit("alice", 5)
+
+		assertErrorIs(t, store.Transfer("alice", "bob", 6), InsufficientFundsError)
+		assertErrorIs(t, store.Transfer("alice", "carol", -1), InvalidAmountError)
+		assertErrorIs(t, store.Transfer("nobody", "dave", 1), WalletNotFoundError)
+		for _, id := range []string{"bob", "carol", "dave"} {
+			if _, err := store.Balance(id); !errors.Is(err, WalletNotFoundError) {
+				t.Errorf("wallet %s: got %v, want %v", id, err, WalletNotFoundError)
+			}
+		}
+	})
+
+	t.Run("statement", func(t *testing.T) {
+		store := newStore(t)
+		_, _ = store.Deposit("alice", 20)
+		_, _ = store.Withdraw("alice", 5)
+		_, _ = store.Withdraw("alice", 50)
+		assertNoError(t, store.Transfer("alice", "bob", 3))
+
+		s, err := store.Statement("alice", time.Time{}, time.Now().Add(time.Minute))
+		assertNoError(t, err)
+		kinds := []TransactionKind{}
+		for _, tx := range s.Transactions {
+			kinds = append(kinds, tx.Kind)
+		}
+		want := []TransactionKind{DepositTransaction, WithdrawTransaction, TransferOutTransaction}
+		if fmt.Sprint(kinds) != fmt.Sprint(want) {
+			t.Errorf("got %v, want %v", kinds, want)
+		}
+		assertBitcoin(t, s.OpeningBalance, 0)
+		assertBitcoin(t, s.ClosingBalance, 12)
+
+		_, err = store.Statement("nobody", time.Time{}, time.Now())
+		if !errors.Is(err, WalletNotFoundError) {
+			t.Errorf("got %v, want %v", err, WalletNotFoundError)
+		}
+	})
+}
+
+func TestInMemoryWalletStore(t *testing.T) {
+	testWalletStore(t, func(t *testing.T) WalletStore {
+		return NewInMemoryWalletStore()
+	})
+}
+
+func TestFileSystemWalletStore(t *testing.T) {
+	testWalletStore(t, func(t *testing.T) WalletStore {
+		store, err := FileSystemWalletStoreFromFile(filepath.Join(t.TempDir(), "wallets.json"))
+		assertNoError(t, err)
+		return store
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "wallets.json")
+		store, err := FileSystemWalletStoreFromFile(path)
+		assertNoError(t, err)
+		_, _ = store.Deposit("alice", 20)
+		assertNoError(t, store.Transfer("alice", "bob", 5))
+
+		reopened, err := FileSystemWalletStoreFromFile(path)
+		assertNoError(t, err)
+		alice, _ := reopened.Balance("alice")
+		bob, _ := reopened.Balance("bob")
+		assertBitcoin(t, alice, 15)
+		assertBitcoin(t, bob, 5)
+
+		s, err := reopened.Statement("bob", time.Time{}, time.Now().Add(time.Minute))
+		assertNoError(t, err)
+		if len(s.Transactions) != 1 || s.Transactions[0].Kind != TransferInTransaction {
+			t.Errorf("history should survive a restart: %+v", s.Transactions)
+		}
+	})
+
+	t.Run("legacy balance-only file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "wallets.json")
+		assertNoError(t, os.WriteFile(path, []byte(`{"alice": 15}`), 0o644))
+		store, err := FileSystemWalletStoreFromFile(path)
+		assertNoError(t, err)
+		balance, err := store.Withdraw("alice", 5)
+		assertNoError(t, err)
+		assertBitcoin(t, balance, 10)
+	})
+
+	t.Run("failed save keeps memory unchanged", func(t *testing.T) {
+		store, err := FileSystemWalletStoreFromFile(filepath.Join(t.TempDir(), "missing-dir", "wallets.json"))
+		assertNoError(t, err)
+
+		_, err = store.Deposit("alice", 20)
+		if err == nil {
+			t.Fatal("expected save to fail")
+		}
+		_, err = store.Balance("alice")
+		if !errors.Is(err, WalletNotFoundError) {
+			t.Errorf("got %v, want %v", err, WalletNotFoundError)
+		}
+
+		// 已有的钱包落盘失败时余额和流水都回滚
+		store.wallets["bob"] = &Wallet{balance: 10}
+		_, err = store.Withdraw("bob", 4)
+		if err == nil {
+			t.Fatal("expected save to fail")
+		}
+		bob, _ := store.Balance("bob")
+		assertBitcoin(t, bob, 10)
+		if h := store.wallets["bob"].History(); len(h) != 0 {
+			t.Errorf("got %+v", h)
+		}
+	})
+}
@@ -3,10 +3,23 @@ package wallets
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Wallet 可以被多个协程并发使用，所有对 balance 的读写都需要持有 mu
 type Wallet struct {
+	// id 转账时决定加锁顺序，第一次转账时分配，放在第一个字段保证 32 位平台上 64 位原子操作的对齐
+	id      uint64
+	mu      sync.Mutex
 	balance Bitcoin
+	// overdraft 允许透支的额度，0 表示不允许透支
+	overdraft Bitcoin
+	// history 每一笔成功的余额变动
+	history []Transaction
+	// now 记录流水时使用的时钟，为 nil 时使用 time.Now，测试中可以替换
+	now func() time.Time
 }
 
 type Bitcoin int
@@ -15,22 +28,116 @@ func (b Bitcoin) String() string {
 	return fmt.Sprintf("%d BTC", b)
 }
 
-func (w *Wallet) Deposit(amount Bitcoin) {
-	fmt.Println("wallet.balance address in method", &w.balance)
+// walletSeq 分配 Wallet.id
+var walletSeq uint64
+
+// seq 返回钱包的编号，零值的 Wallet 在第一次调用时分配
+func (w *Wallet) seq() uint64 {
+	if id := atomic.LoadUint64(&w.id); id != 0 {
+		return id
+	}
+	atomic.CompareAndSwapUint64(&w.id, 0, atomic.AddUint64(&walletSeq, 1))
+	return atomic.LoadUint64(&w.id)
+}
+
+// Deposit 存款，金额必须为正数
+func (w *Wallet) Deposit(amount Bitcoin) error {
+	return w.DepositWithMemo(amount, "")
+}
+
+// DepositWithMemo 存款并在流水中记录备注
+func (w *Wallet) DepositWithMemo(amount Bitcoin, memo string) error {
+	if amount <= 0 {
+		return fmt.Errorf("deposit %s: %w", amount, InvalidAmountError)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deposit(amount, DepositTransaction, memo)
+	return nil
+}
+
+// deposit 调用方需持有 w.mu
+func (w *Wallet) deposit(amount Bitcoin, kind TransactionKind, memo string) {
 	w.balance += amount
+	w.record(kind, amount, memo)
 }
 
 func (w *Wallet) Balance() Bitcoin {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.balance
 }
 
+// SetOverdraftLimit 设置透支额度，设置后余额最低可以到 -limit
+func (w *Wallet) SetOverdraftLimit(limit Bitcoin) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.overdraft = limit
+}
+
+func (w *Wallet) OverdraftLimit() Bitcoin {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.overdraft
+}
+
 var InsufficientFundsError = errors.New("cannot withdraw, insufficient funds")
 
+// Withdraw 检查余额与扣款在同一把锁内完成，避免 check-then-act 竞态。
+// 金额必须为正数，否则负数的取款就成了不受检查的存款。
 func (w *Wallet) Withdraw(amount Bitcoin) error {
-	if amount > w.balance {
+	return w.WithdrawWithMemo(amount, "")
+}
+
+// WithdrawWithMemo 取款并在流水中记录备注，余额不足时不产生流水
+func (w *Wallet) WithdrawWithMemo(amount Bitcoin, memo string) error {
+	if amount <= 0 {
+		return fmt.Errorf("withdraw %s: %w", amount, InvalidAmountError)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.withdraw(amount, WithdrawTransaction, memo)
+}
+
+// withdraw 调用方需持有 w.mu
+func (w *Wallet) withdraw(amount Bitcoin, kind TransactionKind, memo string) error {
+	if amount > w.balance+w.overdraft {
 		return InsufficientFundsError
 	}
 
 	w.balance -= amount
+	w.record(kind, -amount, memo)
+	return nil
+}
+
+// Transfer 从 from 转账到 to，扣款和入账要么都成功，要么都不发生。
+// 两把锁总是按钱包编号从小到大的顺序获取，因此 A->B 与 B->A 并发转账也不会死锁。
+func Transfer(from, to *Wallet, amount Bitcoin) error {
+	if amount <= 0 {
+		return fmt.Errorf("transfer %s: %w", amount, InvalidAmountError)
+	}
+	if from == to {
+		// 自己转给自己，只需要确认余额足够
+		from.mu.Lock()
+		defer from.mu.Unlock()
+		if amount > from.balance+from.overdraft {
+			return InsufficientFundsError
+		}
+		return nil
+	}
+
+	first, second := from, to
+	if second.seq() < first.seq() {
+		first, second = second, first
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if err := from.withdraw(amount, TransferOutTransaction, ""); err != nil {
+		return err
+	}
+	to.deposit(amount, TransferInTransaction, "")
 	return nil
 }
@@ -1,7 +1,10 @@
 package wallets
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
 	"testing"
 )
 
@@ -15,7 +18,7 @@ import (
 //当函数返回一个的指针，你需要确保检查过它是否为 nil，否则你可能会抛出一个执行异常，编译器在这里不能帮到你
 //nil 非常适合描述一个可能丢失的值
 
-func assertBalance(t *testing.T, wallet Wallet, want Bitcoin) {
+func assertBalance(t *testing.T, wallet *Wallet, want Bitcoin) {
 	got := wallet.Balance()
 
 	if got != want {
@@ -46,13 +49,13 @@ func TestWallet(t *testing.T) {
 		wallet := Wallet{}
 		fmt.Println("wallet.balance address in test", &wallet.balance)
 		wallet.Deposit(10)
-		assertBalance(t, wallet, Bitcoin(10))
+		assertBalance(t, &wallet, Bitcoin(10))
 	})
 
 	t.Run("withdraw", func(t *testing.T) {
 		wallet := Wallet{balance: Bitcoin(20)}
 		err := wallet.Withdraw(10)
-		assertBalance(t, wallet, Bitcoin(10))
+		assertBalance(t, &wallet, Bitcoin(10))
 		assertNoError(t, err)
 	})
 
@@ -61,10 +64,118 @@ func TestWallet(t *testing.T) {
 		wallet := Wallet{balance: startBalance}
 		err := wallet.Withdraw(100)
 
-		assertBalance(t, wallet, startBalance)
+		assertBalance(t, &wallet, startBalance)
 		assertError(t, err, InsufficientFundsError)
 	})
 
+	t.Run("non-positive amounts", func(t *testing.T) {
+		wallet := Wallet{balance: Bitcoin(20)}
+		for _, amount := range []Bitcoin{0, -5} {
+			if err := wallet.Deposit(amount); !errors.Is(err, InvalidAmountError) {
+				t.Errorf("Deposit(%s) = %v, want %v", amount, err, InvalidAmountError)
+			}
+			// 负数的取款不能变成存款
+			if err := wallet.Withdraw(amount); !errors.Is(err, InvalidAmountError) {
+				t.Errorf("Withdraw(%s) = %v, want %v", amount, err, InvalidAmountError)
+			}
+		}
+		assertBalance(t, &wallet, Bitcoin(20))
+		if len(wallet.History()) != 0 {
+			t.Errorf("rejected amounts should not be recorded: %v", wallet.History())
+		}
+	})
+}
+
+func TestOverdraft(t *testing.T) {
+	wallet := Wallet{balance: Bitcoin(10)}
+	wallet.SetOverdraftLimit(5)
+
+	assertNoError(t, wallet.Withdraw(15))
+	assertBalance(t, &wallet, Bitcoin(-5))
+	assertError(t, wallet.Withdraw(1), InsufficientFundsError)
+}
+
+func TestTransfer(t *testing.T) {
+	t.Run("transfer", func(t *testing.T) {
+		from, to := Wallet{balance: 20}, Wallet{balance: 5}
+
+		assertNoError(t, Transfer(&from, &to, 15))
+		assertBalance(t, &from, Bitcoin(5))
+		assertBalance(t, &to, Bitcoin(20))
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		from, to := Wallet{balance: 20}, Wallet{}
+
+		assertError(t, Transfer(&from, &to, 21), InsufficientFundsError)
+		assertBalance(t, &from, Bitcoin(20))
+		assertBalance(t, &to, Bitcoin(0))
+	})
+
+	t.Run("non-positive amount", func(t *testing.T) {
+		from, to := Wallet{balance: 20}, Wallet{}
+
+		for _, amount := range []Bitcoin{0, -1} {
+			err := Transfer(&from, &to, amount)
+			if !errors.Is(err, InvalidAmountError) {
+				t.Errorf("got %v, want %v", err, InvalidAmountError)
+			}
+		}
+	})
+
+	t.Run("to self", func(t *testing.T) {
+		wallet := Wallet{balance: 20}
+
+		assertNoError(t, Transfer(&wallet, &wallet, 20))
+		assertError(t, Transfer(&wallet, &wallet, 21), InsufficientFundsError)
+		assertBalance(t, &wallet, Bitcoin(20))
+	})
+}
+
+// go test -race -run TestTransferConservesMoney .
+// 大量协程在少量钱包之间随机双向转账，既验证不会死锁，也验证总金额守恒
+func TestTransferConservesMoney(t *testing.T) {
+	const (
+		walletCount   = 8
+		transfers     = 5000
+		startBalance  = Bitcoin(100)
+		overdraftUsed = Bitcoin(20)
+	)
+
+	wallets := make([]*Wallet, walletCount)
+	for i := range wallets {
+		wallets[i] = &Wallet{balance: startBalance}
+		if i%2 == 0 {
+			wallets[i].SetOverdraftLimit(overdraftUsed)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			from, to := wallets[r.Intn(walletCount)], wallets[r.Intn(walletCount)]
+			err := Transfer(from, to, Bitcoin(1+r.Intn(50)))
+			if err != nil && err != InsufficientFundsError {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	var total Bitcoin
+	for i, w := range wallets {
+		balance := w.Balance()
+		if balance < -w.OverdraftLimit() {
+			t.Errorf("wallet %d balance %s exceeds overdraft limit %s", i, balance, w.OverdraftLimit())
+		}
+		total += balance
+	}
+	if want := startBalance * walletCount; total != want {
+		t.Errorf("money not conserved: got %s, want %s", total, want)
+	}
 }
 
 // wallet.balance address in test 0x140001161d0